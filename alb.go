@@ -0,0 +1,51 @@
+package main
+
+import "strconv"
+
+// albFields names the fields of an Application Load Balancer access log
+// line, in order. Classic ELB lines have no type and end after
+// ssl_protocol; their backend_* fields are read by the target_* names.
+var albFields = []string{"type", "time", "elb", "client", "target",
+	"request_processing_time", "target_processing_time", "response_processing_time",
+	"elb_status_code", "target_status_code", "received_bytes", "sent_bytes",
+	"request", "user_agent", "ssl_cipher", "ssl_protocol", "target_group_arn",
+	"trace_id", "domain_name", "chosen_cert_arn", "matched_rule_priority",
+	"request_creation_time", "actions_executed", "redirect_url", "error_reason",
+	"target_list", "target_status_code_list", "classification", "classification_reason"}
+
+// albField returns the field key of an ALB or classic ELB access log line:
+// one of albFields; method, path or route, taken from the request (see
+// requestField); or a 1-based field number. Processing times of -1, logged
+// when the target didn't respond, are no value.
+func albField(line, key string) (string, bool) {
+	fields := splitAccessLog(line)
+	// classic ELB lines start with the time
+	shift := 0
+	if len(fields) > 0 && len(fields[0]) > 0 && fields[0][0] >= '0' && fields[0][0] <= '9' {
+		shift = 1
+	}
+	switch key {
+	case "method", "path", "route":
+		return requestField(fields, 12-shift, key)
+	case "backend", "backend_processing_time", "backend_status_code":
+		key = "target" + key[len("backend"):]
+	}
+	// the position in the ALB layout
+	pos := -1
+	for j, name := range albFields {
+		if name == key {
+			pos = j
+		}
+	}
+	if n, err := strconv.Atoi(key); err == nil && n > 0 {
+		pos = n - 1 + shift
+	}
+	i := pos - shift
+	if pos < 0 || i < 0 || i >= len(fields) {
+		return "", false
+	}
+	if fields[i] == "-1" && pos >= 5 && pos <= 7 {
+		return "", false
+	}
+	return fields[i], true
+}
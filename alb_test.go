@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestALBField(t *testing.T) {
+	alb := `https 2024-03-01T10:00:00.123456Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.012 0.000 200 200 34 366 "GET https://www.example.com:443/api/users?page=2 HTTP/1.1" "curl/8.4.0" - - arn:tg "Root=1-5833" "www.example.com" "-" 1 2024-03-01T10:00:00.111000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+	elb := `2024-03-01T10:00:00.123456Z my-elb 192.168.131.39:2817 - -1 -1 -1 504 0 0 0 "GET http://www.example.com:80/ HTTP/1.1" "curl/8.4.0" - -`
+	tests := []struct {
+		line, key, want string
+		ok              bool
+	}{
+		{alb, "type", "https", true},
+		{alb, "time", "2024-03-01T10:00:00.123456Z", true},
+		{alb, "target_processing_time", "0.012", true},
+		{alb, "elb_status_code", "200", true},
+		{alb, "route", "GET /api/users?page=2", true},
+		{alb, "path", "/api/users?page=2", true},
+		{alb, "user_agent", "curl/8.4.0", true},
+		{alb, "classification_reason", "-", true},
+		{alb, "7", "0.012", true},
+		{elb, "type", "", false},
+		{elb, "time", "2024-03-01T10:00:00.123456Z", true},
+		{elb, "elb_status_code", "504", true},
+		{elb, "backend_status_code", "0", true},
+		{elb, "target_processing_time", "", false},
+		{elb, "6", "", false},
+		{elb, "route", "GET /", true},
+		{elb, "ssl_protocol", "-", true},
+		{elb, "target_group_arn", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := albField(tt.line, tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("albField(%.5s…, %s) = %q, %v; want %q, %v", tt.line, tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestALBFormat(t *testing.T) {
+	buf := captureLog(t)
+	defer func(format, verb, ts, value, unit string) {
+		*lineFormat, *verbKey, *timeKey, *valueKey, *inputUnit = format, verb, ts, value, unit
+		valueScale = 1
+	}(*lineFormat, *verbKey, *timeKey, *valueKey, *inputUnit)
+	*lineFormat = "alb"
+	if err := checkLineFormat(); err != nil {
+		t.Fatal(err)
+	}
+	valueScale, _ = parseInputUnit(*inputUnit)
+
+	verbs, _ := parseVerbs("GET /api")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("testdata/alb.log", verbs, TimeWindow{}, c)
+	values := processLines(c, nil)
+	if len(values.Values) != 1 || values.Values[0] != 12 {
+		t.Errorf("Values = %v, want [12] ms", values.Values)
+	}
+	if buf.Len() == 0 {
+		t.Error("the request without a target response was not reported")
+	}
+}
+
+func TestALBUserAgent(t *testing.T) {
+	defer func(format string) { *lineFormat = format }(*lineFormat)
+	*lineFormat = "alb"
+	data, err := os.ReadFile("testdata/alb.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(data), "\n")
+	// the last quoted field of ALB lines is not the user agent
+	for i, want := range []string{"api-client", "browser", "api-client"} {
+		if got := groupByUserAgent.Keys(nil, LineMatch{Line: lines[i]}); len(got) != 1 || got[0] != want {
+			t.Errorf("line %d: user-agent class %q, want %s", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Anonymizer replaces endpoint names with stable pseudonyms for reports
+// shared outside the team. Pseudonyms are keyed hashes, so they can't be
+// reversed by hashing guessed names; the key and the pseudonym of every
+// name seen are kept in a local mapping file, which makes them stable
+// across runs and lets the owner translate them back.
+type Anonymizer struct {
+	path  string
+	key   []byte
+	names map[string]string // pseudonym -> name
+}
+
+// anon is the -anonymize mapping, or nil; main sets it.
+var anon *Anonymizer
+
+// loadAnonymizer reads the mapping file at path, or starts a new one with a
+// random key if it doesn't exist yet.
+func loadAnonymizer(path string) (*Anonymizer, error) {
+	a := &Anonymizer{path: path, names: make(map[string]string)}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		a.key = make([]byte, 16)
+		_, err := rand.Read(a.key)
+		return a, err
+	}
+	if err != nil {
+		return nil, &ConfigError{"anonymize", err}
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		pseudonym, name, ok := strings.Cut(text, " ")
+		switch {
+		case !ok:
+			return nil, &ConfigError{"anonymize", fmt.Errorf("%s:%d: want \"<pseudonym> <name>\"", path, line)}
+		case pseudonym == "key":
+			if a.key, err = hex.DecodeString(name); err != nil {
+				return nil, &ConfigError{"anonymize", fmt.Errorf("%s:%d: bad key: %v", path, line, err)}
+			}
+		default:
+			a.names[pseudonym] = name
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &ConfigError{"anonymize", err}
+	}
+	if a.key == nil {
+		return nil, &ConfigError{"anonymize", fmt.Errorf("%s: no key line", path)}
+	}
+	return a, nil
+}
+
+// Name returns the pseudonym of name.
+func (a *Anonymizer) Name(name string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(name))
+	pseudonym := hex.EncodeToString(mac.Sum(nil)[:4])
+	a.names[pseudonym] = name
+	return pseudonym
+}
+
+// Row returns the pseudonym of a report row's name, keeping the "-" of
+// rows for lines without one.
+func (a *Anonymizer) Row(name string) string {
+	if name == "-" {
+		return name
+	}
+	return a.Name(name)
+}
+
+// Path replaces each segment of a path prefix, as used by -path-tree,
+// separately so that the tree keeps its shape. Placeholders like {id} are
+// kept.
+func (a *Anonymizer) Path(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if s != "" && !strings.HasPrefix(s, "{") {
+			segments[i] = a.Name(s)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// renameGroups returns groups keyed by rename of their names, or nil for
+// nil.
+func renameGroups(groups map[string]*AggregatedValues, rename func(string) string) map[string]*AggregatedValues {
+	if groups == nil {
+		return nil
+	}
+	renamed := make(map[string]*AggregatedValues, len(groups))
+	for name, group := range groups {
+		renamed[rename(name)] = group
+	}
+	return renamed
+}
+
+// Save writes the key and every pseudonym seen so far to the mapping file.
+func (a *Anonymizer) Save() error {
+	pseudonyms := make([]string, 0, len(a.names))
+	for p := range a.names {
+		pseudonyms = append(pseudonyms, p)
+	}
+	sort.Strings(pseudonyms)
+
+	tmp := a.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "# metrics -anonymize mapping, keep it private\nkey %s\n", hex.EncodeToString(a.key))
+	for _, p := range pseudonyms {
+		fmt.Fprintf(w, "%s %s\n", p, a.names[p])
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.path)
+}
+
+// anonymizeError replaces the line or value that err quotes by its
+// pseudonym with -anonymize.
+func anonymizeError(err error) error {
+	if anon == nil {
+		return err
+	}
+	switch e := err.(type) {
+	case *ParseError:
+		field := anon.Name(e.Field)
+		inner := e.Err
+		if ne, ok := inner.(*strconv.NumError); ok {
+			// it quotes the field too
+			inner = &strconv.NumError{Func: ne.Func, Num: field, Err: ne.Err}
+		}
+		return &ParseError{Line: anon.Name(e.Line), Field: field, Err: inner}
+	case *TimestampError:
+		return &TimestampError{Line: anon.Name(e.Line)}
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.map")
+	a, err := loadAnonymizer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := a.Name("GET /api/users")
+	if get == "GET /api/users" || len(get) != 8 || a.Name("GET /api/users") != get {
+		t.Errorf("Name = %q, want a stable 8 character pseudonym", get)
+	}
+	tree := a.Path("/api/{id}/orders")
+	if want := "/" + a.Name("api") + "/{id}/" + a.Name("orders"); tree != want {
+		t.Errorf("Path = %q, want %q", tree, want)
+	}
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a later run with the same mapping file gives the same pseudonyms
+	b, err := loadAnonymizer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.names[get] != "GET /api/users" || b.Name("GET /api/users") != get {
+		t.Errorf("mapping not reloaded: %v", b.names)
+	}
+	other, _ := loadAnonymizer(filepath.Join(t.TempDir(), "other.map"))
+	if other.Name("GET /api/users") == get {
+		t.Error("pseudonyms don't depend on the key")
+	}
+}
+
+func TestAnonymizeTop(t *testing.T) {
+	dir := t.TempDir()
+	var b strings.Builder
+	for i, verb := range []string{"DELETE", "GET", "PATCH", "POST", "PUT"} {
+		for j := 0; j <= i; j++ {
+			b.WriteString(verb + " /api 10\n")
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "access.log"), []byte(b.String()), 0644)
+
+	out := runMetrics(t, dir, "", "-anonymize=names.map", "-top=3", "DELETE,GET,PATCH,POST,PUT", "access.log")
+	var rows []string
+	for _, m := range regexp.MustCompile(`(?m)^([0-9a-f]{8}|\(remaining \d+\)) `).FindAllStringSubmatch(out, -1) {
+		rows = append(rows, m[1])
+	}
+	if len(rows) != 4 || rows[3] != "(remaining 2)" {
+		t.Fatalf("want 3 pseudonyms and (remaining 2), got %q in:\n%s", rows, out)
+	}
+	if !sort.StringsAreSorted(rows[:3]) {
+		t.Errorf("rows %q are not ordered by pseudonym", rows[:3])
+	}
+	mapping, _ := os.ReadFile(filepath.Join(dir, "names.map"))
+	if strings.Contains(string(mapping), "remaining") {
+		t.Errorf("the (remaining) row was anonymized:\n%s", mapping)
+	}
+}
+
+func TestAnonymizeGroupByKey(t *testing.T) {
+	dir := t.TempDir()
+	lines := `{"route":"/internal/billing","ms":12}
+{"route":"/internal/payroll","ms":30}
+{"ms":5}
+{"route":"/internal/billing","ms":"secret-value"}
+`
+	os.WriteFile(filepath.Join(dir, "app.log"), []byte(lines), 0644)
+	out := runMetrics(t, dir, "", "-anonymize=names.map", "-format=json", "-value-key=ms",
+		"-group-by=key", "-group-key=route", "ms", "app.log")
+	for _, name := range []string{"billing", "payroll", "secret-value"} {
+		if strings.Contains(out, name) {
+			t.Errorf("%s shown with -anonymize:\n%s", name, out)
+		}
+	}
+	a, _ := loadAnonymizer(filepath.Join(dir, "names.map"))
+	for _, name := range []string{"/internal/billing", "/internal/payroll", "secret-value"} {
+		if !strings.Contains(out, a.Name(name)) {
+			t.Errorf("no pseudonym of %s in:\n%s", name, out)
+		}
+	}
+	// lines without the key keep the "-" row
+	if !regexp.MustCompile(`(?m)^- `).MatchString(out) {
+		t.Errorf("no - row in:\n%s", out)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"time"
+)
+
+// arrivalTracker turns matched lines into the time since the previous line
+// of the same verb, for -inter-arrival.
+type arrivalTracker struct {
+	format     TimestampFormat
+	haveFormat bool
+	last       map[string]time.Time
+}
+
+func newArrivalTracker() *arrivalTracker {
+	return &arrivalTracker{last: make(map[string]time.Time)}
+}
+
+// Time returns the timestamp of line, detecting the format from the first
+// line that has one.
+func (a *arrivalTracker) Time(line string, obj jsonObject) (time.Time, error) {
+	subject := timeSubject(line, obj)
+	if !a.haveFormat {
+		a.format, a.haveFormat = detectTimestampFormat(subject)
+	}
+	ts, ok := a.format.Parse(subject)
+	if !ok || !a.haveFormat {
+		return time.Time{}, &TimestampError{line}
+	}
+	return ts, nil
+}
+
+// Gap returns the milliseconds between ts and the previous line of verb;
+// ok is false for the first one. A line stamped before its predecessor, as
+// happens when writers interleave, counts as a zero gap.
+func (a *arrivalTracker) Gap(verb string, ts time.Time) (gap float32, ok bool) {
+	last, seen := a.last[verb]
+	if !seen || ts.After(last) {
+		a.last[verb] = ts
+	}
+	if !seen || !ts.After(last) {
+		return 0, seen
+	}
+	return float32(ts.Sub(last)) / float32(time.Millisecond), true
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInterArrival(t *testing.T) {
+	captureLog(t)
+	*interArrival = true
+	defer func() { *interArrival = false }()
+
+	c := make(chan LineMatch, 8)
+	c <- LineMatch{Line: "2024-03-01T10:00:00Z GET /a 10", Verb: "GET"}
+	c <- LineMatch{Line: "2024-03-01T10:00:01Z POST /b 10", Verb: "POST"}
+	c <- LineMatch{Line: "2024-03-01T10:00:02Z GET /c 10", Verb: "GET"}
+	c <- LineMatch{Line: "2024-03-01T10:00:01.5Z GET /d 10", Verb: "GET"} // out of order
+	c <- LineMatch{Line: "no timestamp GET 10", Verb: "GET"}
+	c <- LineMatch{Line: "2024-03-01T10:00:07Z GET /e 10", Verb: "GET"}
+	c <- LineMatch{Line: "2024-03-01T10:00:01.25Z POST /f 10", Verb: "POST"}
+	close(c)
+
+	values := processLines(c, nil)
+	if want := (Float32Slice{2000, 0, 5000, 250}); !reflect.DeepEqual(values.Values, want) {
+		t.Errorf("Values = %v, want %v", values.Values, want)
+	}
+	if want := map[string]int{"GET": 3, "POST": 1}; !reflect.DeepEqual(values.Counts, want) {
+		t.Errorf("Counts = %v, want %v", values.Counts, want)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	choices := []string{"name", "count", "min", "avg", "max"}
+	for _, p := range PERCENTILES {
+		choices = append(choices, "p"+strconv.Itoa(p))
+	}
+	flagChoices["sort-by"] = choices
+	flagChoices["group-by"] = []string{"verb", "file", "network", "user-agent", "size", "key"}
+}
+
+// Grouping decides which breakdown rows a matched line is counted in.
+type Grouping struct {
+	Label string
+	// Keys appends the row names for m to dst. A line may be counted in
+	// any number of rows.
+	Keys func(dst []string, m LineMatch) []string
+	// NameLess orders rows when sorting by name; nil means alphabetical.
+	NameLess func(a, b string) bool
+}
+
+var groupByVerb = &Grouping{
+	Label: "verb",
+	Keys: func(dst []string, m LineMatch) []string {
+		return append(dst, m.Verb)
+	},
+}
+
+// groupByKey counts each line under the value of its key field, as read
+// by -format, or under "-" if it has none.
+func groupByKey(key string) *Grouping {
+	return &Grouping{
+		Label: key,
+		Keys: func(dst []string, m LineMatch) []string {
+			v, ok := lineField(m.Line, m.Object, key)
+			if !ok || v == "" {
+				v = "-"
+			}
+			return append(dst, v)
+		},
+	}
+}
+
+// newGrouping returns the Grouping selected by -group-by.
+func newGrouping(name string) (*Grouping, error) {
+	switch name {
+	case "verb":
+		return groupByVerb, nil
+	case "file":
+		return groupByFile, nil
+	case "network":
+		if *networksFile == "" {
+			return nil, &ConfigError{"group-by", errors.New("network grouping needs -networks")}
+		}
+		networks, err := loadNetworks(*networksFile)
+		if err != nil {
+			return nil, err
+		}
+		return groupByNetwork(networks), nil
+	case "user-agent":
+		return groupByUserAgent, nil
+	case "size":
+		if *sizeField == 0 {
+			return nil, &ConfigError{"group-by", errors.New("size grouping needs -size-field")}
+		}
+		bounds, err := parseSizeBuckets(*sizeBuckets)
+		if err != nil {
+			return nil, err
+		}
+		return groupBySize(*sizeField, bounds), nil
+	case "key":
+		if *groupKey == "" {
+			return nil, &ConfigError{"group-by", errors.New("key grouping needs -group-key")}
+		}
+		return groupByKey(*groupKey), nil
+	}
+	return nil, &ConfigError{"group-by", fmt.Errorf("unknown grouping %q", name)}
+}
+
+// GroupPercentiles are the percentiles of a single row of the breakdown.
+type GroupPercentiles struct {
+	Name string
+	PercentileValues
+}
+
+// SortKey selects the column breakdown rows are ordered by. Percentile is
+// only set when Field is "p".
+type SortKey struct {
+	Field      string
+	Percentile int
+}
+
+func parseSortKey(s string, percentiles []int) (SortKey, error) {
+	switch s {
+	case "name", "count", "min", "avg", "max":
+		return SortKey{Field: s}, nil
+	}
+	if strings.HasPrefix(s, "p") {
+		p, err := strconv.Atoi(s[1:])
+		if err == nil {
+			for _, have := range percentiles {
+				if have == p {
+					return SortKey{Field: "p", Percentile: p}, nil
+				}
+			}
+			return SortKey{}, &ConfigError{"sort-by", fmt.Errorf("percentile %d is not computed", p)}
+		}
+	}
+	return SortKey{}, &ConfigError{"sort-by", fmt.Errorf("unknown column %q", s)}
+}
+
+func computeGroupPercentiles(values AggregatedValues, percentiles []int) []GroupPercentiles {
+	groups := make([]GroupPercentiles, 0, len(values.Groups))
+	for name, group := range values.Groups {
+		groups = append(groups, GroupPercentiles{name, computePercentiles(*group, percentiles)})
+	}
+	return groups
+}
+
+// sortGroups orders groups by key, breaking ties by name so output is
+// deterministic. Names are compared with nameLess, or alphabetically if nil.
+func sortGroups(groups []GroupPercentiles, key SortKey, desc bool, nameLess func(a, b string) bool) {
+	if nameLess == nil {
+		nameLess = func(a, b string) bool { return a < b }
+	}
+	column := func(g GroupPercentiles) float32 {
+		switch key.Field {
+		case "count":
+			return float32(g.Count)
+		case "min":
+			return g.Min
+		case "avg":
+			return g.Average
+		case "max":
+			return g.Max
+		case "p":
+			return g.Percentiles[key.Percentile]
+		}
+		return 0
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if key.Field != "name" {
+			if ca, cb := column(a), column(b); ca != cb {
+				return (ca < cb) != desc
+			}
+		}
+		return nameLess(a.Name, b.Name) != (desc && key.Field == "name")
+	})
+}
+
+// limitGroups keeps the first top rows of the sorted groups and folds the
+// values of all others into a single trailing row.
+func limitGroups(groups []GroupPercentiles, values AggregatedValues, top int, percentiles []int) []GroupPercentiles {
+	if top <= 0 || len(groups) <= top {
+		return groups
+	}
+	var rest AggregatedValues
+	for _, g := range groups[top:] {
+		group := values.Groups[g.Name]
+		rest.Values = append(rest.Values, group.Values...)
+		rest.Accum += group.Accum
+	}
+	name := fmt.Sprintf("(remaining %d)", len(groups)-top)
+	return append(groups[:top:top], GroupPercentiles{name, computePercentiles(rest, percentiles)})
+}
+
+func printGroups(label string, groups []GroupPercentiles, percentiles []int) {
+	header := []string{label, "count", "min", "avg", "max"}
+	for _, p := range lowTail {
+		header = append(header, formatLowTail(p))
+	}
+	for _, p := range percentiles {
+		header = append(header, fmt.Sprintf("P%d%%", p))
+	}
+	for _, t := range underThresholds {
+		header = append(header, "<="+formatThreshold(t))
+	}
+	rows := [][]string{header}
+	for _, g := range groups {
+		row := []string{g.Name, strconv.Itoa(g.Count),
+			fmt.Sprintf("%.3f", g.Min), fmt.Sprintf("%.3f", g.Average), fmt.Sprintf("%.3f", g.Max)}
+		for _, v := range g.LowTail {
+			row = append(row, fmt.Sprintf("%.3f", v))
+		}
+		for _, p := range percentiles {
+			row = append(row, fmt.Sprintf("%.3f", g.Percentiles[p]))
+		}
+		for _, share := range g.Under {
+			row = append(row, formatShare(share))
+		}
+		rows = append(rows, row)
+	}
+
+	// padded by hand rather than with tabwriter, which would count the
+	// color escapes towards the column width
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	var b strings.Builder
+	b.WriteString("\n")
+	for r, row := range rows {
+		for i, cell := range row {
+			if p := i - 5 - len(lowTail); r > 0 && p >= 0 && p < len(percentiles) {
+				cell = colorize(groups[r-1].Percentiles[percentiles[p]], cell)
+			}
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-len(row[i])+4))
+			}
+		}
+		b.WriteString("\n")
+	}
+	log.Print(b.String())
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSortKey(t *testing.T) {
+	percentiles := []int{50, 99}
+	tests := []struct {
+		in      string
+		want    SortKey
+		wantErr bool
+	}{
+		{"name", SortKey{Field: "name"}, false},
+		{"count", SortKey{Field: "count"}, false},
+		{"avg", SortKey{Field: "avg"}, false},
+		{"p99", SortKey{Field: "p", Percentile: 99}, false},
+		{"p90", SortKey{}, true},
+		{"pxx", SortKey{}, true},
+		{"median", SortKey{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSortKey(tt.in, percentiles)
+		if tt.wantErr {
+			var cerr *ConfigError
+			if !errors.As(err, &cerr) {
+				t.Errorf("parseSortKey(%q) err = %v, want ConfigError", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseSortKey(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestSortGroups(t *testing.T) {
+	row := func(name string, count int, p99 float32) GroupPercentiles {
+		return GroupPercentiles{name, PercentileValues{
+			Count:       count,
+			Percentiles: map[int]float32{99: p99},
+		}}
+	}
+	groups := []GroupPercentiles{
+		row("b", 10, 5), row("a", 10, 9), row("c", 3, 5), row("d", 1, 1),
+	}
+	tests := []struct {
+		key  SortKey
+		desc bool
+		want []string
+	}{
+		{SortKey{Field: "name"}, false, []string{"a", "b", "c", "d"}},
+		{SortKey{Field: "name"}, true, []string{"d", "c", "b", "a"}},
+		{SortKey{Field: "count"}, false, []string{"d", "c", "a", "b"}},
+		{SortKey{Field: "count"}, true, []string{"a", "b", "c", "d"}},
+		{SortKey{Field: "p", Percentile: 99}, true, []string{"a", "b", "c", "d"}},
+		{SortKey{Field: "p", Percentile: 99}, false, []string{"d", "b", "c", "a"}},
+	}
+	for _, tt := range tests {
+		sorted := append([]GroupPercentiles(nil), groups...)
+		sortGroups(sorted, tt.key, tt.desc, nil)
+		var names []string
+		for _, g := range sorted {
+			names = append(names, g.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("sortGroups(%v, desc=%v) = %v, want %v", tt.key, tt.desc, names, tt.want)
+		}
+	}
+}
+
+func TestLimitGroups(t *testing.T) {
+	values := AggregatedValues{Groups: map[string]*AggregatedValues{
+		"a": {Values: Float32Slice{1, 2}, Accum: 3},
+		"b": {Values: Float32Slice{10}, Accum: 10},
+		"c": {Values: Float32Slice{4, 6}, Accum: 10},
+	}}
+	percentiles := []int{50, 100}
+	groups := computeGroupPercentiles(values, percentiles)
+	sortGroups(groups, SortKey{Field: "name"}, false, nil)
+
+	if got := limitGroups(groups, values, 0, percentiles); len(got) != 3 {
+		t.Errorf("top=0 kept %d rows, want 3", len(got))
+	}
+	if got := limitGroups(groups, values, 3, percentiles); len(got) != 3 {
+		t.Errorf("top=3 kept %d rows, want 3", len(got))
+	}
+
+	got := limitGroups(groups, values, 1, percentiles)
+	if len(got) != 2 || got[0].Name != "a" {
+		t.Fatalf("top=1 = %v, want [a, remaining]", got)
+	}
+	rest := got[1]
+	if rest.Name != "(remaining 2)" || rest.Count != 3 || rest.Min != 4 || rest.Max != 10 || rest.Average != 20.0/3 {
+		t.Errorf("remaining row = %+v", rest)
+	}
+}
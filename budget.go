@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Budget is a latency budget for -budget: the total the verbs share, e.g.
+// of a page load, and the allowances of the verbs given one.
+type Budget struct {
+	Total      float32
+	Allowances map[string]float32
+}
+
+// budget is the -budget, or nil.
+var budget *Budget
+
+// parseBudget parses -budget: the total, optionally followed by
+// comma-separated "verb=allowance" pairs.
+func parseBudget(s string) (*Budget, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	total, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 32)
+	if err != nil || total <= 0 {
+		return nil, &ConfigError{"budget", fmt.Errorf("bad total %q, want a positive value first", parts[0])}
+	}
+	b := &Budget{Total: float32(total), Allowances: make(map[string]float32)}
+	for _, part := range parts[1:] {
+		i := strings.LastIndexByte(part, '=')
+		if i < 0 || strings.TrimSpace(part[:i]) == "" {
+			return nil, &ConfigError{"budget", fmt.Errorf("want verb=allowance, not %q", part)}
+		}
+		verb := strings.TrimSpace(part[:i])
+		v, err := strconv.ParseFloat(strings.TrimSpace(part[i+1:]), 32)
+		if err != nil || v <= 0 {
+			return nil, &ConfigError{"budget", fmt.Errorf("bad allowance %q", part)}
+		}
+		if _, dup := b.Allowances[verb]; dup {
+			return nil, &ConfigError{"budget", fmt.Errorf("more than one allowance for %q", verb)}
+		}
+		b.Allowances[verb] = float32(v)
+	}
+	return b, nil
+}
+
+// BudgetRow is how much of the -budget a verb takes at P50 and P95. A verb
+// is over budget when its P95 is above its allowance, or above the total
+// for verbs without one.
+type BudgetRow struct {
+	Verb      string
+	Count     int
+	P50, P95  float32
+	Allowance float32
+	Over      bool
+}
+
+// budgetRows returns a row for each verb with values, in the order given,
+// and one for all of them, assuming they are called one after the other.
+// names are the reported names of verbs.
+func budgetRows(verbs, names []string, values AggregatedValues, b *Budget) []BudgetRow {
+	var rows []BudgetRow
+	all := BudgetRow{Verb: "all verbs", Allowance: b.Total}
+	for i, verb := range verbs {
+		group := values.Verbs[verb]
+		if group == nil {
+			continue
+		}
+		p := computePercentiles(*group, []int{50, 95})
+		allowance, ok := b.Allowances[verb]
+		if !ok {
+			allowance = b.Total
+		}
+		row := BudgetRow{names[i], p.Count, p.Percentiles[50], p.Percentiles[95], allowance, p.Percentiles[95] > allowance}
+		rows = append(rows, row)
+		all.Count += row.Count
+		all.P50 += row.P50
+		all.P95 += row.P95
+	}
+	all.Over = all.P95 > all.Allowance
+	return append(rows, all)
+}
+
+var budgetHeader = []string{"verb", "count", "P50%", "share", "P95%", "share", "allowance", "status"}
+
+// budgetCells formats a row, with the shares of the total budget.
+func budgetCells(row BudgetRow, b *Budget) []string {
+	status := "ok"
+	if row.Over {
+		status = "over"
+	}
+	return []string{row.Verb, strconv.Itoa(row.Count),
+		fmt.Sprintf("%.3f", row.P50), formatShare(row.P50 / b.Total),
+		fmt.Sprintf("%.3f", row.P95), formatShare(row.P95 / b.Total),
+		formatThreshold(row.Allowance), status}
+}
+
+func printBudget(rows []BudgetRow, b *Budget) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\nshare of the %s budget:\n", formatThreshold(b.Total))
+	tw := tabwriter.NewWriter(&sb, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(budgetHeader, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(budgetCells(row, b), "\t"))
+	}
+	tw.Flush()
+	log.Print(sb.String())
+}
+
+func writeMarkdownBudget(w io.Writer, rows []BudgetRow, b *Budget) {
+	fmt.Fprintf(w, "\nShare of the %s budget:\n\n| %s |\n|---|---:|---:|---:|---:|---:|---:|---|\n",
+		formatThreshold(b.Total), strings.Join(budgetHeader, " | "))
+	for _, row := range rows {
+		cells := budgetCells(row, b)
+		cells[0] = strings.ReplaceAll(cells[0], "|", `\|`)
+		if row.Over {
+			cells[len(cells)-1] = "**over**"
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseBudget(t *testing.T) {
+	b, err := parseBudget("1000, GET /api=250")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Total != 1000 || len(b.Allowances) != 1 || b.Allowances["GET /api"] != 250 {
+		t.Errorf("got %+v", b)
+	}
+	for _, bad := range []string{"fast", "0", "GET=100", "1000,GET", "1000,=5", "1000,GET=x", "1000,GET=1,GET=2"} {
+		if _, err := parseBudget(bad); err == nil {
+			t.Errorf("%q was accepted", bad)
+		}
+	}
+}
+
+func TestBudgetRows(t *testing.T) {
+	values := AggregatedValues{Verbs: make(map[string]*AggregatedValues)}
+	for i := 1; i <= 100; i++ {
+		addToGroup(values.Verbs, "GET /a", float32(i))
+		addToGroup(values.Verbs, "GET /b", float32(i)*5)
+	}
+	b := &Budget{Total: 400, Allowances: map[string]float32{"GET /a": 90}}
+	rows := budgetRows([]string{"GET /a", "GET /b", "GET /c"}, []string{"a", "b", "c"}, values, b)
+	want := []BudgetRow{
+		{"a", 100, 51, 96, 90, true},
+		{"b", 100, 255, 480, 400, true},
+		{"all verbs", 200, 306, 576, 400, true},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got rows %+v", rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d is %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	var out bytes.Buffer
+	writeMarkdownBudget(&out, rows, b)
+	if !strings.Contains(out.String(), "| a | 100 | 51.000 | 12.75% | 96.000 | 24.00% | 90 | **over** |") {
+		t.Errorf("unexpected markdown:\n%s", out.String())
+	}
+}
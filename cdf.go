@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CDFPoint is one step of an empirical CDF: the share of values at or
+// below Value.
+type CDFPoint struct {
+	Value    float32 `json:"value"`
+	Fraction float64 `json:"fraction"`
+}
+
+// CDF is the empirical distribution of one verb or breakdown row.
+type CDF struct {
+	Name   string     `json:"name"`
+	Count  int        `json:"count"`
+	Points []CDFPoint `json:"points"`
+}
+
+// computeCDF returns the CDF of sorted. With points > 0 it is downsampled to
+// the values at that many evenly spaced fractions, the last one being the
+// maximum; otherwise it has one point for each distinct value.
+func computeCDF(sorted []float32, points int) []CDFPoint {
+	n := len(sorted)
+	if n == 0 {
+		return nil
+	}
+	var cdf []CDFPoint
+	if points > 0 && points < n {
+		for i := 1; i <= points; i++ {
+			// the value below which at least i/points of the values lie
+			at := (i*n + points - 1) / points
+			cdf = append(cdf, CDFPoint{sorted[at-1], float64(at) / float64(n)})
+		}
+		return cdf
+	}
+	for i, v := range sorted {
+		if i+1 < n && sorted[i+1] == v {
+			continue
+		}
+		cdf = append(cdf, CDFPoint{v, float64(i+1) / float64(n)})
+	}
+	return cdf
+}
+
+// computeCDFs returns the CDF of each group, ordered by name with nameLess,
+// or alphabetically if nil.
+func computeCDFs(groups map[string]*AggregatedValues, points int, nameLess func(a, b string) bool) []CDF {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	if nameLess == nil {
+		sort.Strings(names)
+	} else {
+		sort.Slice(names, func(i, j int) bool { return nameLess(names[i], names[j]) })
+	}
+	cdfs := make([]CDF, 0, len(names))
+	for _, name := range names {
+		group := groups[name]
+		group.Values.Sort()
+		cdfs = append(cdfs, CDF{name, scaleCount(len(group.Values)), computeCDF(group.Values, points)})
+	}
+	return cdfs
+}
+
+// writeCDFs writes cdfs to path, as JSON if it ends in .json and as CSV
+// rows of label, value and fraction otherwise.
+func writeCDFs(path, label string, cdfs []CDF) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = writeCDFJSON(f, cdfs)
+	} else {
+		err = writeCDFCSV(f, label, cdfs)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeCDFJSON(w io.Writer, cdfs []CDF) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cdfs)
+}
+
+func writeCDFCSV(w io.Writer, label string, cdfs []CDF) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{label, "value", "fraction"})
+	for _, c := range cdfs {
+		for _, p := range c.Points {
+			cw.Write([]string{c.Name,
+				strconv.FormatFloat(float64(p.Value), 'g', -1, 32),
+				strconv.FormatFloat(p.Fraction, 'g', 6, 64)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
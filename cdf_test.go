@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestComputeCDF(t *testing.T) {
+	sorted := []float32{1, 2, 2, 3, 4, 5, 6, 7, 8, 10}
+	full := computeCDF(sorted, 0)
+	if len(full) != 9 || full[1] != (CDFPoint{2, 0.3}) || full[8] != (CDFPoint{10, 1}) {
+		t.Errorf("full CDF = %v", full)
+	}
+	got := computeCDF(sorted, 4)
+	want := []CDFPoint{{2, 0.3}, {4, 0.5}, {7, 0.8}, {10, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CDF with 4 points = %v, want %v", got, want)
+	}
+	if got := computeCDF(nil, 4); got != nil {
+		t.Errorf("CDF of no values = %v", got)
+	}
+}
+
+func TestWriteCDFCSV(t *testing.T) {
+	groups := map[string]*AggregatedValues{
+		"POST": {Values: Float32Slice{20, 10}},
+		"GET":  {Values: Float32Slice{1.5}},
+	}
+	cdfs := computeCDFs(groups, 0, nil)
+	var b bytes.Buffer
+	if err := writeCDFCSV(&b, "verb", cdfs); err != nil {
+		t.Fatal(err)
+	}
+	want := "verb,value,fraction\nGET,1.5,1\nPOST,10,0.5\nPOST,20,1\n"
+	if b.String() != want {
+		t.Errorf("CSV = %q, want %q", b.String(), want)
+	}
+}
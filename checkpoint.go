@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint is how far a file was read, and a hash of its first HeadSize
+// bytes to tell whether a later file by the same name is the same one.
+type Checkpoint struct {
+	Offset   int64  `json:"offset"`
+	HeadSize int64  `json:"head_size"`
+	Head     string `json:"head"`
+}
+
+// Checkpoints is the -state file: the checkpoint of each plain local file
+// read, by absolute path.
+type Checkpoints struct {
+	path   string
+	resume bool
+	Files  map[string]Checkpoint
+}
+
+// checkpoints is the -state file, or nil.
+var checkpoints *Checkpoints
+
+// loadCheckpoints reads the state file at path, if it exists. With resume,
+// files are read from their checkpoints.
+func loadCheckpoints(path string, resume bool) (*Checkpoints, error) {
+	c := &Checkpoints{path: path, resume: resume, Files: make(map[string]Checkpoint)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, &ConfigError{"state", err}
+	}
+	if err := json.Unmarshal(data, &c.Files); err != nil {
+		return nil, &ConfigError{"state", err}
+	}
+	return c, nil
+}
+
+// hashPrefix returns the hash of the first n bytes of f.
+func hashPrefix(f *os.File, n int64) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(f, 0, n)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func checkpointKey(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filename
+	}
+	return abs
+}
+
+// ResumeOffset returns the offset to resume reading f at: its checkpoint,
+// or 0 without -resume or if the file was truncated or replaced since.
+func (c *Checkpoints) ResumeOffset(filename string, f *os.File) int64 {
+	cp, ok := c.Files[checkpointKey(filename)]
+	if !c.resume || !ok || cp.Offset == 0 {
+		return 0
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return 0
+	}
+	if head, err := hashPrefix(f, cp.HeadSize); err != nil || head != cp.Head || fi.Size() < cp.Offset {
+		log.Printf("%s: replaced or truncated since the last run, reading from the start", filename)
+		return 0
+	}
+	return cp.Offset
+}
+
+// Set records that f was read up to offset.
+func (c *Checkpoints) Set(filename string, f *os.File, offset int64) error {
+	size := min(offset, indexHeadSize)
+	head, err := hashPrefix(f, size)
+	if err != nil {
+		return err
+	}
+	c.Files[checkpointKey(filename)] = Checkpoint{offset, size, head}
+	return nil
+}
+
+// Save writes the state file, replacing the old one only once complete.
+func (c *Checkpoints) Save() error {
+	data, err := json.MarshalIndent(c.Files, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
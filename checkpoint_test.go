@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResume(t *testing.T) {
+	captureLog(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	state := filepath.Join(dir, "state.json")
+	defer func() { checkpoints = nil }()
+
+	verbs, _ := parseVerbs("GET")
+	run := func() []float32 {
+		var err error
+		if checkpoints, err = loadCheckpoints(state, true); err != nil {
+			t.Fatal(err)
+		}
+		c := make(chan LineMatch, ChanSize)
+		go filterValues(path, verbs, TimeWindow{}, c)
+		values := processLines(c, nil)
+		if err := checkpoints.Save(); err != nil {
+			t.Fatal(err)
+		}
+		return values.Values
+	}
+	appendTo := func(text string) {
+		f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+		f.WriteString(text)
+		f.Close()
+	}
+
+	appendTo("GET /a 1\nGET /a 2\nGET /a 3")
+	if got := run(); len(got) != 2 {
+		t.Errorf("first run read %v, want the 2 complete lines", got)
+	}
+	appendTo("0\nGET /a 4\n")
+	if got := run(); len(got) != 2 || got[0] != 30 || got[1] != 4 {
+		t.Errorf("second run read %v, want [30 4]", got)
+	}
+	if got := run(); len(got) != 0 {
+		t.Errorf("run without new lines read %v", got)
+	}
+
+	// rotated: a new file by the same name is read from the start
+	os.WriteFile(path, []byte("GET /b 5\nGET /b 6\nGET /b 7\nGET /b 8\nGET /b 9\n"), 0644)
+	if got := run(); len(got) != 5 {
+		t.Errorf("run after rotation read %v, want all 5 lines", got)
+	}
+
+	// without -resume the unterminated line is read, but not checkpointed
+	os.Remove(state)
+	os.WriteFile(path, []byte("GET /a 1\nGET /a 2\nGET /a 3"), 0644)
+	checkpoints, _ = loadCheckpoints(state, false)
+	c := make(chan LineMatch, ChanSize)
+	go filterValues(path, verbs, TimeWindow{}, c)
+	if got := processLines(c, nil).Values; len(got) != 3 {
+		t.Errorf("run without -resume read %v, want all 3 lines", got)
+	}
+	if cp := checkpoints.Files[checkpointKey(path)]; cp.Offset != 18 {
+		t.Errorf("checkpoint at offset %d, want 18 before the unterminated line", cp.Offset)
+	}
+	checkpoints.Save()
+	appendTo("0\n")
+	if got := run(); len(got) != 1 || got[0] != 30 {
+		t.Errorf("resumed run read %v, want [30]", got)
+	}
+
+	// a scan starting after -since seeking still records the end of the file
+	var b strings.Builder
+	for i := 0; i < 5000; i++ {
+		b.WriteString("2024-01-01T00:00:00Z GET 1\n")
+	}
+	b.WriteString("2024-01-02T00:00:00Z GET 2\n")
+	os.WriteFile(path, []byte(b.String()), 0644)
+	checkpoints, _ = loadCheckpoints(state, false)
+	c = make(chan LineMatch, ChanSize)
+	window := TimeWindow{Since: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Sorted: true}
+	go filterValues(path, verbs, window, c)
+	processLines(c, nil)
+	if cp, size := checkpoints.Files[checkpointKey(path)], int64(b.Len()); cp.Offset != size {
+		t.Errorf("checkpoint after seeking to -since at offset %d, want the size %d", cp.Offset, size)
+	}
+}
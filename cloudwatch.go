@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerSource(&Source{Scheme: "cloudwatch", Open: openCloudWatch})
+}
+
+// queryWindow is the -since/-until window, which sources that can query a
+// time range ask for instead of everything; main sets it.
+var queryWindow TimeWindow
+
+// cloudWatchArgs returns the aws logs filter-log-events arguments for a
+// cloudwatch://<log group>?filter=<pattern>&stream=<prefix> URI. Log
+// groups usually start with a slash, as in cloudwatch:///aws/lambda/api.
+func cloudWatchArgs(uri string, window TimeWindow) ([]string, error) {
+	group, query, _ := strings.Cut(strings.TrimPrefix(uri, "cloudwatch://"), "?")
+	if group == "" {
+		return nil, &ConfigError{"input", fmt.Errorf("%s: no log group", uri)}
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, &ConfigError{"input", fmt.Errorf("%s: %v", uri, err)}
+	}
+	args := []string{"logs", "filter-log-events", "--output", "json", "--no-paginate", "--log-group-name", group}
+	if filter := params.Get("filter"); filter != "" {
+		args = append(args, "--filter-pattern", filter)
+	}
+	if stream := params.Get("stream"); stream != "" {
+		args = append(args, "--log-stream-name-prefix", stream)
+	}
+	if !window.Since.IsZero() {
+		args = append(args, "--start-time", strconv.FormatInt(window.Since.UnixMilli(), 10))
+	}
+	if !window.Until.IsZero() {
+		// the end time is inclusive, -until is not
+		args = append(args, "--end-time", strconv.FormatInt(window.Until.UnixMilli()-1, 10))
+	}
+	return args, nil
+}
+
+// openCloudWatch reads the events of a log group, a page at a time.
+func openCloudWatch(uri string) (io.ReadCloser, error) {
+	args, err := cloudWatchArgs(uri, queryWindow)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(fetchCloudWatch(args, pw))
+	}()
+	return pr, nil
+}
+
+// cloudWatchPage holds the fields of a filter-log-events response used
+// here.
+type cloudWatchPage struct {
+	Events []struct {
+		Timestamp int64  `json:"timestamp"`
+		Message   string `json:"message"`
+	} `json:"events"`
+	NextToken string `json:"nextToken"`
+}
+
+// fetchCloudWatch writes the events of every page to w. With -format=text
+// each line is "<RFC 3339 time> <message>", so that -since and -until find
+// a timestamp; other formats get the messages as they are.
+func fetchCloudWatch(args []string, w io.Writer) error {
+	token := ""
+	for {
+		pageArgs := args
+		if token != "" {
+			pageArgs = append(args[:len(args):len(args)], "--next-token", token)
+		}
+		out, err := commandOutput(awsCommand, pageArgs...)
+		if err != nil {
+			return err
+		}
+		var page cloudWatchPage
+		if err := json.Unmarshal(out, &page); err != nil {
+			return fmt.Errorf("cloudwatch: %v", err)
+		}
+		for _, e := range page.Events {
+			stamp := time.UnixMilli(e.Timestamp).UTC().Format(time.RFC3339Nano)
+			// a multi-line message is read as several lines
+			for _, line := range strings.Split(strings.TrimRight(e.Message, "\n"), "\n") {
+				if *lineFormat == "text" {
+					line = stamp + " " + line
+				}
+				if _, err := io.WriteString(w, line+"\n"); err != nil {
+					return err
+				}
+			}
+		}
+		if page.NextToken == "" {
+			return nil
+		}
+		token = page.NextToken
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloudWatchArgs(t *testing.T) {
+	window := TimeWindow{Since: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), Until: time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)}
+	args, err := cloudWatchArgs("cloudwatch:///aws/lambda/api?filter=REPORT&stream=2024/03/01", window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "logs filter-log-events --output json --no-paginate --log-group-name /aws/lambda/api --filter-pattern REPORT --log-stream-name-prefix 2024/03/01 --start-time 1709287200000 --end-time 1709290799999"
+	if got := strings.Join(args, " "); got != want {
+		t.Errorf("got args\n%s\nwant\n%s", got, want)
+	}
+	if _, err := cloudWatchArgs("cloudwatch://?filter=REPORT", TimeWindow{}); err == nil {
+		t.Error("a URI without a log group was accepted")
+	}
+}
+
+func TestCloudWatchSource(t *testing.T) {
+	captureLog(t)
+	fakeCommand(t, &awsCommand, `
+[ "$2" = filter-log-events ] || exit 1
+case "$*" in
+*"--next-token page2"*) printf '%s' '{"events":[{"timestamp":1709287201000,"message":"GET /b 30\n"}]}' ;;
+*) printf '%s' '{"events":[{"timestamp":1709287200000,"message":"GET /a 10\nGET /a 20\n"}],"nextToken":"page2"}' ;;
+esac
+`)
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	since := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	go filterValues("cloudwatch:///aws/lambda/api", verbs, TimeWindow{Since: since}, c)
+	values := processLines(c, nil)
+	if len(values.Values) != 3 || values.Values[2] != 30 {
+		t.Errorf("read %v from CloudWatch, want [10 20 30]", values.Values)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// clusterPercentiles are the quantiles that make up the latency shape of a
+// verb or breakdown row for -clusters.
+var clusterPercentiles = []int{10, 25, 50, 75, 90, 95, 99}
+
+// Cluster is a set of verbs or breakdown rows with similar latency shapes.
+type Cluster struct {
+	// Percentiles are those of the center, in clusterPercentiles order.
+	Percentiles []float32
+	Rows        []string
+}
+
+// latencyShape returns the log-scaled clusterPercentiles of group, so that
+// a row twice as slow is as far away at 10ms as at 1s.
+func latencyShape(group AggregatedValues) []float64 {
+	p := computePercentiles(group, clusterPercentiles)
+	shape := make([]float64, len(clusterPercentiles))
+	for i, percent := range clusterPercentiles {
+		shape[i] = math.Log1p(math.Max(float64(p.Percentiles[percent]), 0))
+	}
+	return shape
+}
+
+func shapeDistance(a, b []float64) float64 {
+	d := 0.0
+	for i := range a {
+		d += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return d
+}
+
+// computeClusters groups the rows of groups into up to k clusters by
+// k-means over their latency shapes. The centers start at the row with the
+// most values and then at the rows farthest from every center so far, so
+// the same input always gives the same clusters. Clusters are ordered by
+// their center's median.
+func computeClusters(groups map[string]*AggregatedValues, k int) []Cluster {
+	var names []string
+	for name, g := range groups {
+		if len(g.Values) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	k = min(k, len(names))
+	if k == 0 {
+		return nil
+	}
+	shapes := make([][]float64, len(names))
+	first := 0
+	for i, name := range names {
+		shapes[i] = latencyShape(*groups[name])
+		if len(groups[name].Values) > len(groups[names[first]].Values) {
+			first = i
+		}
+	}
+
+	nearest := func(centers [][]float64, shape []float64) (int, float64) {
+		best, bestDist := 0, math.Inf(1)
+		for c, center := range centers {
+			if d := shapeDistance(shape, center); d < bestDist {
+				best, bestDist = c, d
+			}
+		}
+		return best, bestDist
+	}
+	centers := [][]float64{append([]float64(nil), shapes[first]...)}
+	for len(centers) < k {
+		farthest, farthestDist := 0, -1.0
+		for i, shape := range shapes {
+			if _, d := nearest(centers, shape); d > farthestDist {
+				farthest, farthestDist = i, d
+			}
+		}
+		centers = append(centers, append([]float64(nil), shapes[farthest]...))
+	}
+
+	assigned := make([]int, len(names))
+	for iter := 0; iter < 100; iter++ {
+		changed := iter == 0
+		for i, shape := range shapes {
+			if c, _ := nearest(centers, shape); c != assigned[i] {
+				assigned[i], changed = c, true
+			}
+		}
+		if !changed {
+			break
+		}
+		for c := range centers {
+			n := 0
+			sum := make([]float64, len(clusterPercentiles))
+			for i, shape := range shapes {
+				if assigned[i] == c {
+					n++
+					for j := range shape {
+						sum[j] += shape[j]
+					}
+				}
+			}
+			if n == 0 {
+				// keeps its center, which no row is closest to
+				continue
+			}
+			for j := range sum {
+				centers[c][j] = sum[j] / float64(n)
+			}
+		}
+	}
+
+	var clusters []Cluster
+	for c, center := range centers {
+		cluster := Cluster{Percentiles: make([]float32, len(center))}
+		for j, v := range center {
+			cluster.Percentiles[j] = float32(math.Expm1(v))
+		}
+		for i, name := range names {
+			if assigned[i] == c {
+				cluster.Rows = append(cluster.Rows, name)
+			}
+		}
+		if len(cluster.Rows) > 0 {
+			sort.Strings(cluster.Rows)
+			clusters = append(clusters, cluster)
+		}
+	}
+	// by P50, the third of clusterPercentiles
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].Percentiles[2] < clusters[j].Percentiles[2]
+	})
+	return clusters
+}
+
+// clusterCells formats the center of cluster i.
+func clusterCells(i int, cluster Cluster) []string {
+	cells := []string{fmt.Sprint(i + 1), fmt.Sprint(len(cluster.Rows))}
+	for _, v := range cluster.Percentiles {
+		cells = append(cells, fmt.Sprintf("%.3f", v))
+	}
+	return cells
+}
+
+func clusterHeader() []string {
+	header := []string{"cluster", "size"}
+	for _, p := range clusterPercentiles {
+		header = append(header, fmt.Sprintf("P%d%%", p))
+	}
+	return header
+}
+
+// printClusters logs the center of each cluster, then its rows.
+func printClusters(label string, clusters []Cluster) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nclusters by latency shape, by %s:\n", label)
+	tw := tabwriter.NewWriter(&b, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(clusterHeader(), "\t"))
+	for i, cluster := range clusters {
+		fmt.Fprintln(tw, strings.Join(clusterCells(i, cluster), "\t"))
+	}
+	tw.Flush()
+	for i, cluster := range clusters {
+		fmt.Fprintf(&b, "  %d: %s\n", i+1, strings.Join(cluster.Rows, ", "))
+	}
+	log.Print(b.String())
+}
+
+func writeMarkdownClusters(w io.Writer, label string, clusters []Cluster) {
+	header := append(clusterHeader(), "members")
+	fmt.Fprintf(w, "\nClusters by latency shape, by %s:\n\n| %s |\n|%s---|\n", label,
+		strings.Join(header, " | "), strings.Repeat("---:|", len(header)-1))
+	for i, cluster := range clusters {
+		rows := strings.ReplaceAll(strings.Join(cluster.Rows, ", "), "|", `\|`)
+		fmt.Fprintf(w, "| %s | %s |\n", strings.Join(clusterCells(i, cluster), " | "), rows)
+	}
+}
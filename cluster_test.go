@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComputeClusters(t *testing.T) {
+	groups := make(map[string]*AggregatedValues)
+	add := func(name string, scale float32, n int) {
+		for i := 1; i <= n; i++ {
+			addToGroup(groups, name, scale*float32(i))
+		}
+	}
+	add("GET /a", 1, 100)
+	add("GET /b", 1.1, 50)
+	add("GET /c", 20, 100)
+	add("GET /d", 22, 100)
+	add("GET /e", 300, 10)
+	groups["GET /empty"] = &AggregatedValues{}
+
+	clusters := computeClusters(groups, 3)
+	var members [][]string
+	for _, c := range clusters {
+		members = append(members, c.Rows)
+	}
+	want := [][]string{{"GET /a", "GET /b"}, {"GET /c", "GET /d"}, {"GET /e"}}
+	if !reflect.DeepEqual(members, want) {
+		t.Errorf("got clusters %q, want %q", members, want)
+	}
+	if again := computeClusters(groups, 3); !reflect.DeepEqual(again, clusters) {
+		t.Errorf("clusters changed between runs: %v, then %v", clusters, again)
+	}
+	if got := computeClusters(groups, 10); len(got) != 5 {
+		t.Errorf("got %d clusters of 5 rows with k=10", len(got))
+	}
+	if got := computeClusters(nil, 3); got != nil {
+		t.Errorf("got clusters %v without rows", got)
+	}
+
+	var out bytes.Buffer
+	writeMarkdownClusters(&out, "verb", clusters)
+	if !strings.Contains(out.String(), "| 3 | 1 | 600.000 |") || !strings.Contains(out.String(), "| GET /c, GET /d |") {
+		t.Errorf("unexpected markdown:\n%s", out.String())
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiRed    = "\x1b[31m"
+	ansiReset  = "\x1b[0m"
+)
+
+// Thresholds split values into green (below Warn), yellow (below Crit) and
+// red.
+type Thresholds struct {
+	Warn float32
+	Crit float32
+}
+
+// cellColors is nil when output should not be colored.
+var cellColors *Thresholds
+
+func parseThresholds(s string) (*Thresholds, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return nil, &ConfigError{"color-thresholds", errors.New("want warn,crit")}
+	}
+	warn, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 32)
+	if err != nil {
+		return nil, &ConfigError{"color-thresholds", err}
+	}
+	crit, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 32)
+	if err != nil {
+		return nil, &ConfigError{"color-thresholds", err}
+	}
+	if crit < warn {
+		return nil, &ConfigError{"color-thresholds", errors.New("crit is below warn")}
+	}
+	return &Thresholds{float32(warn), float32(crit)}, nil
+}
+
+// colorize wraps cell in the color of val, if coloring is enabled.
+func colorize(val float32, cell string) string {
+	if cellColors == nil {
+		return cell
+	}
+	color := ansiGreen
+	if val >= cellColors.Crit {
+		color = ansiRed
+	} else if val >= cellColors.Warn {
+		color = ansiYellow
+	}
+	return color + cell + ansiReset
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
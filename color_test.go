@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseThresholds(t *testing.T) {
+	got, err := parseThresholds("100, 250.5")
+	if err != nil || *got != (Thresholds{100, 250.5}) {
+		t.Errorf("parseThresholds = %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "100", "1,2,3", "a,2", "5,1"} {
+		var cerr *ConfigError
+		if _, err := parseThresholds(bad); !errors.As(err, &cerr) {
+			t.Errorf("parseThresholds(%q) err = %v, want ConfigError", bad, err)
+		}
+	}
+}
+
+func TestColorize(t *testing.T) {
+	cellColors = &Thresholds{Warn: 100, Crit: 500}
+	defer func() { cellColors = nil }()
+
+	tests := []struct {
+		val  float32
+		want string
+	}{
+		{10, ansiGreen + "x" + ansiReset},
+		{100, ansiYellow + "x" + ansiReset},
+		{499, ansiYellow + "x" + ansiReset},
+		{500, ansiRed + "x" + ansiReset},
+	}
+	for _, tt := range tests {
+		if got := colorize(tt.val, "x"); got != tt.want {
+			t.Errorf("colorize(%v) = %q, want %q", tt.val, got, tt.want)
+		}
+	}
+
+	cellColors = nil
+	if got := colorize(1000, "x"); got != "x" {
+		t.Errorf("colorize without thresholds = %q, want plain", got)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Command is a subcommand selected by the first positional argument.
+type Command struct {
+	Name  string
+	Args  string
+	Short string
+	Run   func(args []string)
+}
+
+var commands = make(map[string]*Command)
+
+// flagChoices lists the accepted values of flags that take a fixed set of
+// values, for the completion scripts to offer.
+var flagChoices = make(map[string][]string)
+
+func registerCommand(cmd *Command) {
+	commands[cmd.Name] = cmd
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+const usageExamples = `Examples:
+  # percentiles of the last field of every line containing GET or POST
+  metrics GET,POST access.log
+
+  # verbs can be boolean expressions over "substrings" and ~"regexps"
+  metrics '"GET /api" AND NOT healthcheck,~"(PUT|PATCH) /api"' access.log
+
+  # all rotated logs at once, with a row per file
+  metrics -group-by=file GET 'logs/frontend-*.log'
+
+  # every access log below a directory of dated subdirectories
+  metrics -include='access*.log*' GET /var/log/archive
+
+  # the access logs bundled into a day's archive, without extracting it
+  metrics -include='access*.log*' -group-by=file GET /var/log/archive/2024-03-01.tar.gz
+
+  # a log served over HTTP(S)
+  metrics GET https://logs.internal/frontend/access.log
+
+  # logs in S3, streamed through the aws CLI
+  metrics GET 's3://archive/frontend/2024-03-*.log.gz'
+
+  # logs in Google Cloud Storage, streamed through the gcloud CLI
+  metrics GET 'gs://archive/frontend/2024-03-*.log.gz'
+
+  # a Kafka topic up to its current end, consumed with kcat
+  metrics GET,POST 'kafka://broker1:9092,broker2:9092/access-logs?group=metrics'
+
+  # aggregate syslog sent by other hosts, report on Ctrl-C
+  metrics -listen-syslog :5514 GET,POST
+
+  # lines another process writes, without a temporary file; report when it closes
+  metrics GET,POST unix:///tmp/metrics.sock   # and: producer | nc -U /tmp/metrics.sock
+
+  # the REPORT lines of a Lambda function over the last day, from CloudWatch Logs
+  metrics -since=24h -value-field=6 REPORT 'cloudwatch:///aws/lambda/checkout?filter=REPORT'
+
+  # the pods of a Kubernetes deployment over the last hour, or a Docker container
+  metrics -since=1h GET,POST 'k8s://shop?selector=app%3Dweb'
+  metrics GET,POST docker://web-1
+
+  # a service that logs to the systemd journal
+  metrics -journal -unit frontend.service GET,POST
+
+  # JSON lines: match verbs against .route, read the latency from .http.duration_ms
+  metrics -format=json -verb-key=route -time-key=ts -value-key=http.duration_ms '/api/users' app.log
+
+  # a BigQuery CSV export, one breakdown row per region
+  metrics -format=csv -verb-key=endpoint -time-key=timestamp -value-key=latency_ms -group-by=key -group-key=region GET export.csv
+
+  # plot the full latency distribution of each verb
+  metrics -cdf=cdf.csv -cdf-points=200 GET,POST access.log
+
+  # an nginx access log, one breakdown row per status code
+  metrics -format=nginx -group-by=key -group-key=status "GET /api" /var/log/nginx/access.log
+
+  # load balancer logs straight from S3
+  metrics -format=alb -breakdown "GET /api,POST /api" s3://my-lb-logs/AWSLogs/123456789012/elasticloadbalancing/
+
+  # noisy neighbors: the top tenants by requests, total latency and P99
+  metrics -format=json -value-key=duration_ms -tenant-key=customer_id GET frontend.log
+
+  # keep reading through log rotation, report on Ctrl-C
+  metrics -follow GET,POST /var/log/app/access.log
+
+  # percentiles per plan, looked up by customer ID in customers.csv (customer_id,plan,shard)
+  metrics -format=json -value-key=duration_ms -lookup=customers.csv -lookup-key=customer_id -group-by=key -group-key=plan GET app.log
+
+  # split load balancer time into app and edge time by trace ID
+  metrics -format=alb -join=app.log -join-format=json -join-key=trace_id -join-id-key=request_id -join-value-key=duration_ms -join-unit=ms GET lb.log
+
+  # every 5 minutes from cron, only the lines written since the last run
+  metrics -state=/var/lib/metrics/state.json -resume GET /var/log/app/access.log
+
+  # a 40GB daily log, scanned in 8 shards at once
+  metrics -parallel=8 GET,POST /var/log/app/access.log.1
+
+  # a huge log with few matching lines, without a copy of every line
+  metrics -mmap 'GET /api/export' /var/log/app/access.log
+
+  # which backend calls eat a 1s page load budget, as a table to share
+  metrics -budget='1000,GET /api/search=400' -output=markdown 'GET /api/cart,GET /api/search,GET /api/user' access.log
+
+  # endpoints whose latency looks alike, e.g. behind the same slow dependency
+  metrics -clusters=4 -group-by=key -group-key=route -format=json -value-key=duration_ms GET app.log
+
+  # is the canary slower than the rest of the fleet?
+  metrics -canary='host=canary-' 'GET /api' access.log
+
+  # latency of each arm of an A/B experiment, against its control
+  metrics -variant-key=experiment.bucket -format=json -value-key=duration_ms GET app.log
+
+  # slowest verbs first, as a markdown table for a ticket
+  metrics -breakdown -sort-by=p99 -desc -top=10 -output=markdown GET,POST,PUT access.log
+
+  # report nginx $request_time, logged in seconds, in milliseconds
+  metrics -input-unit=s GET access.log
+
+  # read from a pipe; gzip, bzip2, zstd and lz4 input is decompressed automatically
+  ssh web1 cat /var/log/nginx/access.log.1.gz | metrics GET -
+
+  # share of requests within SLO latencies, per verb
+  metrics -breakdown -under=250,500 GET,POST access.log
+
+  # custom one-line report
+  echo 'p99={{index .Summary.Percentiles 99}}' > p99.tmpl
+  metrics -template p99.tmpl GET access.log
+
+  # guess the layout of an unfamiliar log
+  metrics inspect access.log
+
+  # build a config interactively, then use it
+  metrics init -o nginx.conf access.log
+  metrics -config nginx.conf access.log
+
+  # the same, from ~/.config/metrics/profiles/nginx-prod.conf
+  metrics -profile nginx-prod access.log
+
+  # show version and build info
+  metrics version
+
+  # print a bash completion script
+  metrics completion bash > /etc/bash_completion.d/metrics
+`
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: metrics [flags] <verb>[,<verb>...] <file|glob|->... [region]\n")
+	fmt.Fprintf(out, "       metrics <command> [args]\n\n")
+	fmt.Fprint(out, usageExamples)
+	fmt.Fprintf(out, "\nCommands:\n")
+	for _, name := range commandNames() {
+		cmd := commands[name]
+		fmt.Fprintf(out, "  %-28s %s\n", cmd.Name+" "+cmd.Args, cmd.Short)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// commandUsage prints a one-line usage for cmd and exits.
+func commandUsage(cmd *Command) {
+	fmt.Fprintf(os.Stderr, "Usage: metrics %s %s\n", cmd.Name, cmd.Args)
+	os.Exit(2)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// significance is the p-value below which a variant is reported as
+// significantly slower or faster than the baseline.
+const significance = 0.05
+
+// canaryMatcher matches the lines of the canary for -canary, or is nil;
+// main sets it.
+var canaryMatcher Matcher
+
+// variantOf returns the variant of line that it is compared by: "canary"
+// or "baseline" with -canary, or else the -variant-key field. Lines without
+// the field are in no experiment, and false.
+func variantOf(line string, obj jsonObject) (string, bool) {
+	if canaryMatcher != nil {
+		if canaryMatcher.Match(line) {
+			return "canary", true
+		}
+		return "baseline", true
+	}
+	v, ok := lineField(line, obj, *variantKey)
+	return v, ok && v != ""
+}
+
+// baselineVariant returns the variant the others are compared with.
+func baselineVariant() string {
+	if canaryMatcher != nil {
+		return "baseline"
+	}
+	return *variantBaseline
+}
+
+// VariantComparison compares the values of a variant with the baseline's.
+type VariantComparison struct {
+	Variant, Baseline string
+	// Deltas are the differences of the variant's percentiles from the
+	// baseline's, and Relative the same as a share of the baseline's.
+	Deltas   []float32
+	Relative []float32
+	// P is the two-sided p-value of the Mann-Whitney U test, and Slower
+	// tells whether the variant's values tend to be the larger ones.
+	P      float64
+	Slower bool
+}
+
+// Verdict summarizes the test at the significance level.
+func (c VariantComparison) Verdict() string {
+	switch {
+	case c.P >= significance:
+		return "no significant difference"
+	case c.Slower:
+		return "slower"
+	}
+	return "faster"
+}
+
+// compareVariants compares each of rows but baseline with it, at
+// percentiles. groups holds the values of the rows by name.
+func compareVariants(rows []GroupPercentiles, groups map[string]*AggregatedValues, baseline string, percentiles []int) []VariantComparison {
+	var base *GroupPercentiles
+	for i := range rows {
+		if rows[i].Name == baseline {
+			base = &rows[i]
+		}
+	}
+	if base == nil || base.Count == 0 {
+		return nil
+	}
+	var comparisons []VariantComparison
+	for _, row := range rows {
+		if row.Name == baseline {
+			continue
+		}
+		c := VariantComparison{Variant: row.Name, Baseline: baseline}
+		for _, p := range percentiles {
+			delta := row.Percentiles[p] - base.Percentiles[p]
+			c.Deltas = append(c.Deltas, delta)
+			c.Relative = append(c.Relative, delta/base.Percentiles[p])
+		}
+		c.P, c.Slower = mannWhitney(groups[row.Name].Values, groups[baseline].Values)
+		comparisons = append(comparisons, c)
+	}
+	return comparisons
+}
+
+// mannWhitney returns the two-sided p-value of the Mann-Whitney U test of
+// a and b, by the normal approximation with the correction for ties, and
+// whether the values of a tend to be larger. Neither needs to be normally
+// distributed, which latencies never are.
+func mannWhitney(a, b []float32) (p float64, aLarger bool) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 1, false
+	}
+	type value struct {
+		v     float32
+		fromA bool
+	}
+	all := make([]value, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, value{v, true})
+	}
+	for _, v := range b {
+		all = append(all, value{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// sum the ranks of a, giving tied values the average of their ranks
+	rankSum, ties := 0.0, 0.0
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSum += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+	n := n1 + n2
+	u := rankSum - n1*(n1+1)/2
+	mean := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		// all values are the same
+		return 1, false
+	}
+	z := (u - mean) / sigma
+	return math.Erfc(math.Abs(z) / math.Sqrt2), u > mean
+}
+
+// comparisonCells formats a comparison: the delta at each percentile, with
+// its share of the baseline's value unless that is 0, the p-value and the
+// verdict.
+func comparisonCells(c VariantComparison) []string {
+	cells := []string{c.Variant}
+	for i, d := range c.Deltas {
+		cell := fmt.Sprintf("%+.3f", d)
+		if r := float64(c.Relative[i]); !math.IsInf(r, 0) && !math.IsNaN(r) {
+			cell += fmt.Sprintf(" (%+.1f%%)", r*100)
+		}
+		cells = append(cells, cell)
+	}
+	p := fmt.Sprintf("%.4f", c.P)
+	if c.P < 0.0001 {
+		p = "<0.0001"
+	}
+	return append(cells, p, c.Verdict())
+}
+
+func comparisonHeader(baseline string, percentiles []int) []string {
+	header := []string{"vs " + baseline}
+	for _, p := range percentiles {
+		header = append(header, fmt.Sprintf("P%d%%", p))
+	}
+	return append(header, "p", "verdict")
+}
+
+// printVariants logs the percentiles of each variant, then how each
+// compares with the baseline.
+func printVariants(label string, rows []GroupPercentiles, comparisons []VariantComparison, percentiles []int) {
+	printGroups(label, rows, percentiles)
+	if len(comparisons) == 0 {
+		log.Printf("no %s to compare with the baseline", label)
+		return
+	}
+	var b strings.Builder
+	b.WriteString("\n")
+	tw := tabwriter.NewWriter(&b, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(comparisonHeader(comparisons[0].Baseline, percentiles), "\t"))
+	for _, c := range comparisons {
+		fmt.Fprintln(tw, strings.Join(comparisonCells(c), "\t"))
+	}
+	tw.Flush()
+	fmt.Fprintf(&b, "p is that of the Mann-Whitney U test; below %g the difference is significant\n", significance)
+	log.Print(b.String())
+}
+
+func writeMarkdownVariants(w io.Writer, label string, rows []GroupPercentiles, comparisons []VariantComparison, percentiles []int) {
+	fmt.Fprintln(w)
+	writeMarkdownGroups(w, label, rows, percentiles)
+	if len(comparisons) == 0 {
+		fmt.Fprintf(w, "\nNo %s to compare with the baseline.\n", label)
+		return
+	}
+	header := comparisonHeader(comparisons[0].Baseline, percentiles)
+	fmt.Fprintf(w, "\n| %s |\n|---|%s---|\n", strings.Join(header, " | "), strings.Repeat("---:|", len(header)-2))
+	for _, c := range comparisons {
+		cells := comparisonCells(c)
+		cells[0] = strings.ReplaceAll(cells[0], "|", `\|`)
+		if c.P < significance {
+			cells[len(cells)-1] = "**" + cells[len(cells)-1] + "**"
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+	fmt.Fprintf(w, "\np is that of the Mann-Whitney U test; below %g the difference is significant.\n", significance)
+}
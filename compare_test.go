@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMannWhitney(t *testing.T) {
+	tests := []struct {
+		a, b    []float32
+		p       float64
+		aLarger bool
+	}{
+		{[]float32{1, 2, 3}, []float32{4, 5, 6}, 0.0495, false},
+		{[]float32{4, 5, 6}, []float32{1, 2, 3}, 0.0495, true},
+		// ties: ranks 1.5, 1.5, 3.5, 3.5, 5 and 6
+		{[]float32{1, 2, 3}, []float32{1, 2, 4}, 0.8222, false},
+		{[]float32{7, 7}, []float32{7, 7, 7}, 1, false},
+		{nil, []float32{1}, 1, false},
+	}
+	for _, tt := range tests {
+		p, aLarger := mannWhitney(tt.a, tt.b)
+		if math.Abs(p-tt.p) > 0.0001 || aLarger != tt.aLarger {
+			t.Errorf("mannWhitney(%v, %v) = %.4f, %v; want %.4f, %v", tt.a, tt.b, p, aLarger, tt.p, tt.aLarger)
+		}
+	}
+}
+
+func TestCanary(t *testing.T) {
+	captureLog(t)
+	defer func() { canaryMatcher = nil }()
+	canaryMatcher, _ = parseMatcher("host=canary")
+	var lines []string
+	for i := 1; i <= 200; i++ {
+		lines = append(lines, fmt.Sprintf("GET host=web %d", i))
+		lines = append(lines, fmt.Sprintf("GET host=canary %d", i+50))
+	}
+	c := make(chan LineMatch, ChanSize)
+	go func() {
+		for _, line := range lines {
+			c <- LineMatch{line, "GET", "test", nil}
+		}
+		close(c)
+	}()
+	values := processLines(c, nil)
+	rows := computeGroupPercentiles(AggregatedValues{Groups: values.Variants}, []int{50, 99})
+	sortGroups(rows, SortKey{Field: "name"}, false, nil)
+	comparisons := compareVariants(rows, values.Variants, "baseline", []int{50, 99})
+	if len(comparisons) != 1 {
+		t.Fatalf("got comparisons %+v", comparisons)
+	}
+	cmp := comparisons[0]
+	if cmp.Variant != "canary" || cmp.Deltas[0] != 50 || cmp.Relative[0] != 50.0/101 || cmp.Verdict() != "slower" {
+		t.Errorf("got %+v, %s", cmp, cmp.Verdict())
+	}
+
+	var out bytes.Buffer
+	writeMarkdownVariants(&out, "variant", rows, comparisons, []int{50, 99})
+	if !strings.Contains(out.String(), "| canary | +50.000 (+49.5%) | +50.000 (+25.1%) | <0.0001 | **slower** |") {
+		t.Errorf("unexpected markdown:\n%s", out.String())
+	}
+	if got := compareVariants(rows[1:], values.Variants, "baseline", []int{50}); got != nil {
+		t.Errorf("compared without a baseline: %+v", got)
+	}
+}
+
+func TestVariantKey(t *testing.T) {
+	captureLog(t)
+	defer func(format, value, variant string) {
+		*lineFormat, *valueKey, *variantKey = format, value, variant
+	}(*lineFormat, *valueKey, *variantKey)
+	*lineFormat, *valueKey, *variantKey = "json", "ms", "exp.arm"
+	// lines without the key are in no variant
+	arms := []string{`,"exp":{"arm":"control"}`, `,"exp":{"arm":"b"}`, `,"exp":{"arm":"a"}`, ""}
+	c := make(chan LineMatch, ChanSize)
+	go func() {
+		for i := 0; i < 300; i++ {
+			c <- LineMatch{fmt.Sprintf(`{"ms":%d%s}`, i, arms[i%len(arms)]), "GET", "test", nil}
+		}
+		close(c)
+	}()
+	values := processLines(c, nil)
+	if len(values.Variants) != 3 {
+		t.Fatalf("got variants %v", values.Variants)
+	}
+	if n := len(values.Variants["control"].Values); n != 75 {
+		t.Errorf("control has %d values, want 75", n)
+	}
+	rows := computeGroupPercentiles(AggregatedValues{Groups: values.Variants}, []int{50})
+	sortGroups(rows, SortKey{Field: "name"}, false, nil)
+	comparisons := compareVariants(rows, values.Variants, baselineVariant(), []int{50})
+	if len(comparisons) != 2 || comparisons[0].Variant != "a" || comparisons[1].Variant != "b" || comparisons[0].Baseline != "control" {
+		t.Errorf("got comparisons %+v", comparisons)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerCommand(&Command{
+		Name:  "completion",
+		Args:  "<bash|zsh|fish>",
+		Short: "print a shell completion script",
+		Run:   runCompletion,
+	})
+}
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		commandUsage(commands["completion"])
+	}
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(os.Stdout, flag.CommandLine)
+	case "zsh":
+		writeZshCompletion(os.Stdout, flag.CommandLine)
+	case "fish":
+		writeFishCompletion(os.Stdout, flag.CommandLine)
+	default:
+		commandUsage(commands["completion"])
+	}
+}
+
+type flagInfo struct {
+	Name    string
+	Usage   string
+	IsBool  bool
+	Choices []string
+}
+
+func completionFlags(fs *flag.FlagSet) []flagInfo {
+	var flags []flagInfo
+	fs.VisitAll(func(f *flag.Flag) {
+		info := flagInfo{Name: f.Name, Usage: f.Usage, Choices: flagChoices[f.Name]}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok {
+			info.IsBool = b.IsBoolFlag()
+		}
+		flags = append(flags, info)
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+func writeBashCompletion(w io.Writer, fs *flag.FlagSet) {
+	flags := completionFlags(fs)
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "-" + f.Name
+	}
+
+	fmt.Fprintf(w, "_metrics() {\n")
+	fmt.Fprintf(w, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(w, "    case \"$prev\" in\n")
+	fmt.Fprintf(w, "    completion) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n",
+		strings.Join(completionShells, " "))
+	for _, f := range flags {
+		if len(f.Choices) > 0 {
+			fmt.Fprintf(w, "    -%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n",
+				f.Name, strings.Join(f.Choices, " "))
+		}
+	}
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "    if [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintf(w, "    elif [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	fmt.Fprintf(w, "    else\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -o default -F _metrics metrics\n")
+}
+
+func writeZshCompletion(w io.Writer, fs *flag.FlagSet) {
+	escape := strings.NewReplacer("[", `\[`, "]", `\]`, ":", `\:`, "'", `'\''`)
+
+	fmt.Fprintf(w, "#compdef metrics\n\n")
+	fmt.Fprintf(w, "_arguments \\\n")
+	for _, f := range completionFlags(fs) {
+		spec := fmt.Sprintf("-%s[%s]", f.Name, escape.Replace(f.Usage))
+		switch {
+		case f.IsBool:
+		case len(f.Choices) > 0:
+			spec += fmt.Sprintf(":%s:(%s)", f.Name, strings.Join(f.Choices, " "))
+		default:
+			spec += fmt.Sprintf(":%s:_files", f.Name)
+		}
+		fmt.Fprintf(w, "  '%s' \\\n", spec)
+	}
+	fmt.Fprintf(w, "  '1:command or verbs:(%s)' \\\n", strings.Join(commandNames(), " "))
+	fmt.Fprintf(w, "  '*:file:_files'\n")
+}
+
+func writeFishCompletion(w io.Writer, fs *flag.FlagSet) {
+	for _, name := range commandNames() {
+		fmt.Fprintf(w, "complete -c metrics -n __fish_use_subcommand -a %s -d '%s'\n",
+			name, commands[name].Short)
+	}
+	fmt.Fprintf(w, "complete -c metrics -n '__fish_seen_subcommand_from completion' -x -a '%s'\n",
+		strings.Join(completionShells, " "))
+	for _, f := range completionFlags(fs) {
+		desc := strings.ReplaceAll(f.Usage, "'", `\'`)
+		switch {
+		case f.IsBool:
+			fmt.Fprintf(w, "complete -c metrics -o %s -d '%s'\n", f.Name, desc)
+		case len(f.Choices) > 0:
+			fmt.Fprintf(w, "complete -c metrics -o %s -d '%s' -x -a '%s'\n",
+				f.Name, desc, strings.Join(f.Choices, " "))
+		default:
+			fmt.Fprintf(w, "complete -c metrics -o %s -d '%s' -r\n", f.Name, desc)
+		}
+	}
+}
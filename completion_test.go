@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestCompletionGolden(t *testing.T) {
+	fs := flag.NewFlagSet("metrics", flag.ContinueOnError)
+	fs.String("cpuprofile", "", "write cpu profile to file")
+	fs.Bool("timings", false, "report time spent in each processing stage")
+
+	tests := []struct {
+		shell string
+		write func(io.Writer, *flag.FlagSet)
+	}{
+		{"bash", writeBashCompletion},
+		{"zsh", writeZshCompletion},
+		{"fish", writeFishCompletion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.write(&buf, fs)
+			checkGolden(t, "completion."+tt.shell+".golden", buf.Bytes())
+		})
+	}
+}
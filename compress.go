@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+func init() {
+	features = append(features, "input:gzip", "input:bzip2")
+	features = append(features, commandCompressions()...)
+}
+
+// zstdCommand and lz4Command decompress the formats the standard library
+// has no reader for.
+var (
+	zstdCommand = "zstd"
+	lz4Command  = "lz4"
+)
+
+// commandCompressions returns the input features of the formats read by
+// running a command, for those whose command is installed.
+func commandCompressions() []string {
+	var found []string
+	for _, c := range []struct{ format, command string }{{"zstd", zstdCommand}, {"lz4", lz4Command}} {
+		if _, err := exec.LookPath(c.command); err == nil {
+			found = append(found, "input:"+c.format)
+		}
+	}
+	return found
+}
+
+var compressionMagic = []struct {
+	Name  string
+	Magic []byte
+}{
+	{"gzip", []byte{0x1f, 0x8b}},
+	{"bzip2", []byte("BZh")},
+	{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{"lz4", []byte{0x04, 0x22, 0x4d, 0x18}},
+}
+
+// compression returns the name of the format r is compressed with, judged
+// by its magic bytes rather than the file extension, or "" for plain text.
+func compression(r io.ReaderAt) string {
+	head := make([]byte, 4)
+	n, _ := r.ReadAt(head, 0)
+	return compressionOf(head[:n])
+}
+
+// sniffCompression is compression for readers that can't be read twice,
+// such as stdin. Read from the returned reader instead of r.
+func sniffCompression(r io.Reader) (io.Reader, string) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(4)
+	return br, compressionOf(head)
+}
+
+func compressionOf(head []byte) string {
+	for _, c := range compressionMagic {
+		if bytes.HasPrefix(head, c.Magic) {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// decompress wraps r in a reader for format, as returned by compression.
+// Readers that run a command are io.Closers, to be closed when stopping
+// before the end.
+func decompress(r io.Reader, format string) (io.Reader, error) {
+	switch format {
+	case "gzip":
+		return gzip.NewReader(r)
+	case "bzip2":
+		return bzip2.NewReader(r), nil
+	case "zstd":
+		return decompressCommand(r, format, zstdCommand, "-d", "-c", "-q")
+	case "lz4":
+		return decompressCommand(r, format, lz4Command, "-d", "-c", "-q")
+	}
+	return r, nil
+}
+
+func decompressCommand(r io.Reader, format, name string, args ...string) (io.Reader, error) {
+	cr, err := startFilter(r, name, args...)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s input needs the %s command: %v", format, name, err)
+	}
+	return &commandDecompressor{commandReader: cr}, nil
+}
+
+// commandDecompressor reads the output of a decompressing command. A
+// failure of the command is returned by Read in place of io.EOF.
+type commandDecompressor struct {
+	*commandReader
+	closed bool
+}
+
+func (d *commandDecompressor) Read(p []byte) (int, error) {
+	n, err := d.commandReader.Read(p)
+	if err == io.EOF && !d.closed {
+		d.closed = true
+		if cerr := d.commandReader.Close(); cerr != nil {
+			return n, cerr
+		}
+	}
+	return n, err
+}
+
+// Close stops the command if the output wasn't read to the end.
+func (d *commandDecompressor) Close() error {
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	return d.commandReader.Close()
+}
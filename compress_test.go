@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompressedInput(t *testing.T) {
+	captureLog(t)
+	plain, err := os.ReadFile("testdata/access.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no extension: the format is detected from the magic bytes
+	gz := filepath.Join(t.TempDir(), "access")
+	f, err := os.Create(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write(plain)
+	zw.Close()
+	f.Close()
+
+	verbs, _ := parseVerbs("GET,POST")
+	read := func(path string) AggregatedValues {
+		c := make(chan LineMatch, ChanSize)
+		go filterValues(path, verbs, TimeWindow{}, c)
+		return processLines(c, nil)
+	}
+	want := read("testdata/access.log")
+	for _, tt := range []struct{ path, format string }{{gz, "gzip"}, {"testdata/access.log.bz2", "bzip2"}} {
+		f, err := os.Open(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := compression(f); got != tt.format {
+			t.Errorf("compression(%s) = %q, want %q", tt.path, got, tt.format)
+		}
+		f.Close()
+		if got := read(tt.path); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: values = %+v, want %+v", tt.format, got, want)
+		}
+	}
+}
+
+func TestStdinInput(t *testing.T) {
+	captureLog(t)
+	f, err := os.Open("testdata/access.log.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	saved := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = saved }()
+
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("-", verbs, TimeWindow{}, c)
+	n := 0
+	for range c {
+		n++
+	}
+	if n != 5 {
+		t.Errorf("matched %d lines from stdin, want 5", n)
+	}
+}
+
+func TestCommandCompressions(t *testing.T) {
+	fakeCommand(t, &zstdCommand, "exit 0\n")
+	defer func(name string) { lz4Command = name }(lz4Command)
+	lz4Command = filepath.Join(t.TempDir(), "no-lz4")
+	if got := commandCompressions(); !reflect.DeepEqual(got, []string{"input:zstd"}) {
+		t.Errorf("commandCompressions() = %q, want only input:zstd", got)
+	}
+}
+
+func TestCommandDecompression(t *testing.T) {
+	captureLog(t)
+	// the fake reads the compressed input and prints the log instead
+	fakeCommand(t, &zstdCommand, "cat >/dev/null; cat testdata/access.log\n")
+	path := filepath.Join(t.TempDir(), "access.log.zst")
+	os.WriteFile(path, []byte{0x28, 0xb5, 0x2f, 0xfd, 0}, 0644)
+
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues(path, verbs, TimeWindow{}, c)
+	if values := processLines(c, nil); len(values.Values) != 4 {
+		t.Errorf("read %d GET values from zstd input, want 4", len(values.Values))
+	}
+
+	fakeCommand(t, &lz4Command, "echo 'corrupted frame' >&2; exit 1\n")
+	r, err := decompress(bytes.NewReader([]byte{0x04, 0x22, 0x4d, 0x18}), "lz4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); err == nil || !strings.Contains(err.Error(), "corrupted frame") {
+		t.Errorf("failed decompression read error = %v", err)
+	}
+}
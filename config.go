@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func profilesDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "metrics", "profiles"), nil
+}
+
+// profilePath returns the config file of the named profile, listing the
+// available profiles in the error if it does not exist.
+func profilePath(name string) (string, error) {
+	dir, err := profilesDir()
+	if err != nil {
+		return "", &ConfigError{"profile", err}
+	}
+	path := filepath.Join(dir, name+".conf")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	names, _ := listProfiles(dir)
+	if len(names) == 0 {
+		return "", &ConfigError{"profile", fmt.Errorf("%s not found, and %s has no profiles", name, dir)}
+	}
+	return "", &ConfigError{"profile", fmt.Errorf("%s not found, available: %s", name, strings.Join(names, ", "))}
+}
+
+func listProfiles(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = strings.TrimSuffix(filepath.Base(m), ".conf")
+	}
+	return names, nil
+}
+
+// loadConfig reads a config file of "name = value" lines, where name is a
+// flag name without the dash or "verbs". Blank lines and lines starting
+// with # are ignored.
+func loadConfig(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &ConfigError{"config", err}
+	}
+	defer f.Close()
+
+	config := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, &ConfigError{"config", fmt.Errorf("%s:%d: want \"name = value\"", path, n)}
+		}
+		config[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &ConfigError{"config", err}
+	}
+	return config, nil
+}
+
+// applyConfig sets the flags named in config that were not given on the
+// command line, and returns the configured verbs, if any.
+func applyConfig(fs *flag.FlagSet, config map[string]string) (string, error) {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	for name, value := range config {
+		if name == "verbs" || set[name] {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return "", &ConfigError{"config", fmt.Errorf("unknown setting %q", name)}
+		}
+		if err := fs.Set(name, value); err != nil {
+			return "", &ConfigError{"config", fmt.Errorf("%s: %v", name, err)}
+		}
+	}
+	return config["verbs"], nil
+}
+
+func writeConfig(w io.Writer, config map[string]string) {
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s = %s\n", name, config[name])
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.conf")
+	os.WriteFile(path, []byte("# comment\n\nverbs = GET,POST\nvalue-field = -2\ntop=5\n"), 0644)
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	field := fs.Int("value-field", 0, "")
+	top := fs.Int("top", 0, "")
+	fs.Parse([]string{"-top=2"})
+
+	verbs, err := applyConfig(fs, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verbs != "GET,POST" || *field != -2 {
+		t.Errorf("verbs, value-field = %q, %d, want GET,POST, -2", verbs, *field)
+	}
+	if *top != 2 {
+		t.Errorf("top = %d, command line value 2 should win over config", *top)
+	}
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("value-field", 0, "")
+	var cerr *ConfigError
+	if _, err := applyConfig(fs, map[string]string{"nope": "1"}); !errors.As(err, &cerr) {
+		t.Errorf("unknown setting err = %v, want ConfigError", err)
+	}
+	if _, err := applyConfig(fs, map[string]string{"value-field": "x"}); !errors.As(err, &cerr) {
+		t.Errorf("bad value err = %v, want ConfigError", err)
+	}
+}
+
+func TestWriteConfigRoundTrip(t *testing.T) {
+	want := map[string]string{"verbs": "GET", "value-field": "7"}
+	var buf bytes.Buffer
+	writeConfig(&buf, want)
+	path := filepath.Join(t.TempDir(), "metrics.conf")
+	os.WriteFile(path, buf.Bytes(), 0644)
+	got, err := loadConfig(path)
+	if err != nil || len(got) != 2 || got["verbs"] != "GET" || got["value-field"] != "7" {
+		t.Errorf("round trip = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestProfilePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+	dir, err := profilesDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "nginx-prod.conf"), []byte("verbs = GET\n"), 0644)
+
+	path, err := profilePath("nginx-prod")
+	if err != nil || path != filepath.Join(dir, "nginx-prod.conf") {
+		t.Errorf("profilePath = %q, %v", path, err)
+	}
+	_, err = profilePath("missing")
+	if err == nil || !strings.Contains(err.Error(), "available: nginx-prod") {
+		t.Errorf("missing profile err = %v, want list of available profiles", err)
+	}
+}
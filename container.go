@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// dockerCommand and kubectlCommand read the logs of containers, so that
+// the usual Docker host and Kubernetes context configuration applies.
+var (
+	dockerCommand  = "docker"
+	kubectlCommand = "kubectl"
+)
+
+func init() {
+	registerSource(&Source{Scheme: "docker", Open: openDocker})
+	registerSource(&Source{Scheme: "k8s", Open: openKubernetes})
+}
+
+// dockerArgs returns the docker logs arguments for a docker://<container>
+// URI. With -format=text lines are prefixed with their RFC 3339 time, so
+// that -since and -until find a timestamp.
+func dockerArgs(uri string, window TimeWindow) ([]string, error) {
+	container := strings.TrimPrefix(uri, "docker://")
+	if container == "" || strings.ContainsAny(container, "/?") {
+		return nil, &ConfigError{"input", fmt.Errorf("%s: want docker://<container>", uri)}
+	}
+	args := []string{"logs"}
+	if *lineFormat == "text" {
+		args = append(args, "--timestamps")
+	}
+	if !window.Since.IsZero() {
+		args = append(args, "--since", window.Since.Format(time.RFC3339Nano))
+	}
+	if !window.Until.IsZero() {
+		args = append(args, "--until", window.Until.Format(time.RFC3339Nano))
+	}
+	return append(args, container), nil
+}
+
+// openDocker reads both the stdout and the stderr of a container, as
+// programs log to either.
+func openDocker(uri string) (io.ReadCloser, error) {
+	args, err := dockerArgs(uri, queryWindow)
+	if err != nil {
+		return nil, err
+	}
+	return startMerged(dockerCommand, args...)
+}
+
+// kubectlArgs returns the kubectl logs arguments for a
+// k8s://[namespace/]pod URI, or k8s://[namespace]?selector=app%3Dweb for
+// the pods matching a label selector. Query parameters container and
+// context pick one container, instead of all of them, and a kubeconfig
+// context.
+func kubectlArgs(uri string, window TimeWindow) ([]string, error) {
+	name, query, _ := strings.Cut(strings.TrimPrefix(uri, "k8s://"), "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, &ConfigError{"input", fmt.Errorf("%s: %v", uri, err)}
+	}
+	var namespace, pod string
+	selector := params.Get("selector")
+	switch ns, p, nested := strings.Cut(name, "/"); {
+	case selector != "":
+		namespace = name
+	case nested:
+		namespace, pod = ns, p
+	default:
+		pod = name
+	}
+	if strings.Contains(namespace, "/") || strings.Contains(pod, "/") || (pod == "") == (selector == "") {
+		return nil, &ConfigError{"input", fmt.Errorf("%s: want k8s://[namespace/]pod or k8s://[namespace]?selector=labels", uri)}
+	}
+	args := []string{"logs"}
+	if context := params.Get("context"); context != "" {
+		args = append(args, "--context", context)
+	}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	if pod != "" {
+		args = append(args, pod)
+	} else {
+		// kubectl only shows the last 10 lines of each pod by default
+		args = append(args, "--selector", selector, "--tail=-1")
+	}
+	if container := params.Get("container"); container != "" {
+		args = append(args, "--container", container)
+	} else {
+		args = append(args, "--all-containers")
+	}
+	if *lineFormat == "text" {
+		args = append(args, "--timestamps")
+	}
+	if !window.Since.IsZero() {
+		// there is no end time: -until is up to the local window
+		args = append(args, "--since-time", window.Since.Format(time.RFC3339Nano))
+	}
+	return args, nil
+}
+
+func openKubernetes(uri string) (io.ReadCloser, error) {
+	args, err := kubectlArgs(uri, queryWindow)
+	if err != nil {
+		return nil, err
+	}
+	return startCommand(kubectlCommand, args...)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContainerArgs(t *testing.T) {
+	since := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	window := TimeWindow{Since: since, Until: since.Add(time.Hour)}
+	args, err := dockerArgs("docker://web-1", window)
+	want := "logs --timestamps --since 2024-03-01T10:00:00Z --until 2024-03-01T11:00:00Z web-1"
+	if err != nil || strings.Join(args, " ") != want {
+		t.Errorf("got docker args %q, %v; want %s", args, err, want)
+	}
+
+	tests := []struct{ uri, want string }{
+		{"k8s://web-7d9f", "logs web-7d9f --all-containers --timestamps --since-time 2024-03-01T10:00:00Z"},
+		{"k8s://shop/web-7d9f?container=nginx&context=prod",
+			"logs --context prod --namespace shop web-7d9f --container nginx --timestamps --since-time 2024-03-01T10:00:00Z"},
+		{"k8s://shop?selector=app%3Dweb",
+			"logs --namespace shop --selector app=web --tail=-1 --all-containers --timestamps --since-time 2024-03-01T10:00:00Z"},
+		{"k8s://?selector=app=web", "logs --selector app=web --tail=-1 --all-containers --timestamps --since-time 2024-03-01T10:00:00Z"},
+	}
+	for _, tt := range tests {
+		args, err := kubectlArgs(tt.uri, window)
+		if err != nil || strings.Join(args, " ") != tt.want {
+			t.Errorf("kubectlArgs(%s) = %q, %v; want %s", tt.uri, args, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"docker://", "docker://a/b"} {
+		if _, err := dockerArgs(bad, TimeWindow{}); err == nil {
+			t.Errorf("%s was accepted", bad)
+		}
+	}
+	for _, bad := range []string{"k8s://", "k8s://shop/", "k8s://a/b/c", "k8s://shop/web?selector=app=web"} {
+		if _, err := kubectlArgs(bad, TimeWindow{}); err == nil {
+			t.Errorf("%s was accepted", bad)
+		}
+	}
+}
+
+func TestDockerSource(t *testing.T) {
+	captureLog(t)
+	fakeCommand(t, &dockerCommand, `[ "$3" = web-1 ] || exit 1
+echo '2024-03-01T10:00:00.000000001Z GET /a 200 12.5'
+echo '2024-03-01T10:00:01.000000001Z GET /b 500 40' >&2
+`)
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("docker://web-1", verbs, TimeWindow{}, c)
+	if values := processLines(c, nil); len(values.Values) != 2 {
+		t.Errorf("read %v from stdout and stderr, want 2 values", values.Values)
+	}
+}
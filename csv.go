@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// csvColumns maps the names in the header of the first CSV input to their
+// 0-based column. It is set by scanFile before any line of that file is
+// sent on, and only read afterwards.
+var csvColumns map[string]int
+
+func splitCSV(line string) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(line))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return r.Read()
+}
+
+// setCSVHeader records the column names of the first input's header line,
+// and checks that later inputs have the same columns.
+func setCSVHeader(filename, line string) error {
+	names, err := splitCSV(line)
+	if err != nil {
+		return fmt.Errorf("%s: bad CSV header: %v", filename, err)
+	}
+	if csvColumns == nil {
+		csvColumns = make(map[string]int, len(names))
+		for i, name := range names {
+			csvColumns[strings.TrimSpace(name)] = i
+		}
+		return nil
+	}
+	if len(names) != len(csvColumns) {
+		return fmt.Errorf("%s: CSV header has %d columns, the first input had %d", filename, len(names), len(csvColumns))
+	}
+	for i, name := range names {
+		if csvColumns[strings.TrimSpace(name)] != i {
+			return fmt.Errorf("%s: CSV column %d is %q, which differs from the first input", filename, i+1, name)
+		}
+	}
+	return nil
+}
+
+// csvField returns the column key of a CSV line: a name from the header,
+// or a 1-based column number.
+func csvField(line, key string) (string, bool) {
+	i, ok := csvColumns[key]
+	if !ok {
+		n, err := strconv.Atoi(key)
+		if err != nil || n < 1 {
+			return "", false
+		}
+		i = n - 1
+	}
+	fields, err := splitCSV(line)
+	if err != nil || i >= len(fields) {
+		return "", false
+	}
+	return fields[i], true
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCSVFormat(t *testing.T) {
+	captureLog(t)
+	defer func(format, verb, ts, value string) {
+		*lineFormat, *verbKey, *timeKey, *valueKey = format, verb, ts, value
+		csvColumns = nil
+	}(*lineFormat, *verbKey, *timeKey, *valueKey)
+	*lineFormat, *verbKey, *timeKey, *valueKey = "csv", "endpoint", "1", "latency_ms"
+
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.csv"), filepath.Join(dir, "b.csv")
+	os.WriteFile(a, []byte(`timestamp,endpoint,region,latency_ms
+2024-03-01 10:00:00 UTC,GET /api,eu-west-1,12.5
+2024-03-01 10:00:01 UTC,"POST /api, bulk",eu-west-1,40
+2024-03-01 10:00:02 UTC,GET /api,us-east-1,8
+`), 0644)
+	os.WriteFile(b, []byte(`timestamp,endpoint,region,latency_ms
+2024-03-01 10:00:03 UTC,GET /api,us-east-1,10
+`), 0644)
+
+	verbs, _ := parseVerbs("GET,POST")
+	c := make(chan LineMatch, ChanSize)
+	go filterFiles([]string{a, b}, verbs, TimeWindow{}, c)
+	values := processLines(c, groupByKey("region"))
+	if want := (Float32Slice{12.5, 40, 8, 10}); !reflect.DeepEqual(values.Values, want) {
+		t.Errorf("Values = %v, want %v", values.Values, want)
+	}
+	if len(values.Groups["eu-west-1"].Values) != 2 || len(values.Groups["us-east-1"].Values) != 2 {
+		t.Errorf("Groups = %v", values.Groups)
+	}
+
+	if got, ok := csvField(`x,"a ""quoted"" b",y`, "endpoint"); !ok || got != `a "quoted" b` {
+		t.Errorf("csvField = %q, %v", got, ok)
+	}
+	if err := setCSVHeader("c.csv", "timestamp,endpoint,latency_ms"); err == nil {
+		t.Error("a header with other columns was accepted")
+	}
+}
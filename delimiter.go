@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// recordDelimiter separates input records instead of newlines; main sets
+// it from -delimiter. Empty means lines.
+var recordDelimiter []byte
+
+// parseDelimiter parses -delimiter, which may use Go string escapes such
+// as \x1e or \t, and \0 for NUL.
+func parseDelimiter(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s == `\0` {
+		return []byte{0}, nil
+	}
+	d, err := strconv.Unquote(`"` + s + `"`)
+	if err != nil || d == "" {
+		return nil, &ConfigError{"delimiter", fmt.Errorf("bad delimiter %q", s)}
+	}
+	return []byte(d), nil
+}
+
+// scanRecords returns a bufio.SplitFunc for records ending in delim, or
+// lines if delim is empty.
+func scanRecords(delim []byte) bufio.SplitFunc {
+	if len(delim) == 0 {
+		return bufio.ScanLines
+	}
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, delim); i >= 0 {
+			return i + len(delim), data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
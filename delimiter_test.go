@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDelimiter(t *testing.T) {
+	for s, want := range map[string]string{`\0`: "\x00", `\x1e`: "\x1e", "--": "--", `\r\n`: "\r\n"} {
+		if got, err := parseDelimiter(s); err != nil || string(got) != want {
+			t.Errorf("parseDelimiter(%q) = %q, %v; want %q", s, got, err, want)
+		}
+	}
+	for _, s := range []string{`\`, `"`, `\q`} {
+		if _, err := parseDelimiter(s); err == nil {
+			t.Errorf("parseDelimiter(%q) succeeded", s)
+		}
+	}
+}
+
+func TestScanRecords(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("GET /a\n12\x00\x00GET /b 8"))
+	scanner.Split(scanRecords([]byte{0}))
+	var got []string
+	for scanner.Scan() {
+		got = append(got, scanner.Text())
+	}
+	if want := []string{"GET /a\n12", "", "GET /b 8"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %q, want %q", got, want)
+	}
+}
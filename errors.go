@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// ParseError reports a matched line from which no value could be extracted.
+type ParseError struct {
+	Line  string
+	Field string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("no float:%s, err: %v", e.Field, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// TimestampError reports a line without the timestamp it needs.
+type TimestampError struct {
+	Line string
+}
+
+func (e *TimestampError) Error() string {
+	return "no timestamp in line: " + e.Line
+}
+
+// ConfigError reports an invalid flag or argument.
+type ConfigError struct {
+	Option string
+	Err    error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid %s: %v", e.Option, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// warnf logs a problem that the run can carry on past, or exits with it
+// under -strict.
+func warnf(format string, args ...interface{}) {
+	if *strict {
+		fatalf(format, args...)
+	}
+	log.Printf(format, args...)
+}
+
+// atExit is run by fatal and fatalf before the process exits, which skips
+// the deferred calls of main, e.g. to release the -lock.
+var atExit []func()
+
+// fatal logs v and exits, like log.Fatal, after running atExit.
+func fatal(v ...interface{}) {
+	runAtExit()
+	log.Fatal(v...)
+}
+
+// fatalf logs and exits like log.Fatalf, after running atExit.
+func fatalf(format string, v ...interface{}) {
+	runAtExit()
+	log.Fatalf(format, v...)
+}
+
+func runAtExit() {
+	for i := len(atExit) - 1; i >= 0; i-- {
+		atExit[i]()
+	}
+	atExit = nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Matcher decides whether a line belongs to a verb.
+type Matcher interface {
+	Match(line string) bool
+}
+
+type substringMatcher string
+
+// newSubstringMatcher returns a matcher for s honoring -ignore-case.
+func newSubstringMatcher(s string) Matcher {
+	if *ignoreCase {
+		return newFoldMatcher(s)
+	}
+	return substringMatcher(s)
+}
+
+func (m substringMatcher) Match(line string) bool { return strings.Contains(line, string(m)) }
+
+type regexpMatcher struct{ re *regexp.Regexp }
+
+func (m regexpMatcher) Match(line string) bool { return m.re.MatchString(line) }
+
+type notMatcher struct{ m Matcher }
+
+func (m notMatcher) Match(line string) bool { return !m.m.Match(line) }
+
+type andMatcher []Matcher
+
+func (m andMatcher) Match(line string) bool {
+	for _, sub := range m {
+		if !sub.Match(line) {
+			return false
+		}
+	}
+	return true
+}
+
+type orMatcher []Matcher
+
+func (m orMatcher) Match(line string) bool {
+	for _, sub := range m {
+		if sub.Match(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitVerbDefs splits the verb argument on commas that are not inside
+// quotes or parentheses. Empty definitions are dropped.
+func splitVerbDefs(arg string) []string {
+	var defs []string
+	depth, start := 0, 0
+	inQuote, escaped := false, false
+	for i, c := range arg {
+		switch {
+		case escaped:
+			escaped = false
+		case inQuote && c == '\\':
+			escaped = true
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			defs = append(defs, arg[start:i])
+			start = i + 1
+		}
+	}
+	defs = append(defs, arg[start:])
+
+	kept := defs[:0]
+	for _, d := range defs {
+		if d != "" {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+type exprToken struct {
+	Kind  string // "str", "re", "word", "(", ")", "AND", "OR", "NOT"
+	Value string
+}
+
+// isExpression reports whether def uses the expression syntax. Anything else
+// is a plain substring, matched exactly as written, spaces included.
+func isExpression(def string) bool {
+	if strings.ContainsAny(def, `"()`) || strings.Contains(def, `~"`) {
+		return true
+	}
+	for _, word := range strings.Fields(def) {
+		if word == "AND" || word == "OR" || word == "NOT" {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenizeExpr(s string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, exprToken{Kind: string(c)})
+			i++
+		case c == '"' || (c == '~' && i+1 < len(s) && s[i+1] == '"'):
+			kind := "str"
+			if c == '~' {
+				kind = "re"
+				i++
+			}
+			var b strings.Builder
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, errors.New("unterminated quote")
+			}
+			i++
+			tokens = append(tokens, exprToken{kind, b.String()})
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\"()", rune(s[j])) {
+				j++
+			}
+			word := s[i:j]
+			switch word {
+			case "AND", "OR", "NOT":
+				tokens = append(tokens, exprToken{Kind: word})
+			default:
+				tokens = append(tokens, exprToken{"word", word})
+			}
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].Kind
+	}
+	return ""
+}
+
+// parseExpr parses a verb expression:
+//
+//	expr    = and { "OR" and }
+//	and     = unary { "AND" unary }
+//	unary   = "NOT" unary | primary
+//	primary = "(" expr ")" | '"substring"' | '~"regexp"' | word { word }
+//
+// Consecutive bare words form one substring joined by single spaces.
+func parseExpr(s string) (Matcher, error) {
+	tokens, err := tokenizeExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	m, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.describe())
+	}
+	return m, nil
+}
+
+func (p *exprParser) describe() string {
+	if p.pos >= len(p.tokens) {
+		return "end of expression"
+	}
+	t := p.tokens[p.pos]
+	if t.Value != "" {
+		return fmt.Sprintf("%q", t.Value)
+	}
+	return t.Kind
+}
+
+func (p *exprParser) or() (Matcher, error) {
+	m, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	terms := orMatcher{m}
+	for p.peek() == "OR" {
+		p.pos++
+		if m, err = p.and(); err != nil {
+			return nil, err
+		}
+		terms = append(terms, m)
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *exprParser) and() (Matcher, error) {
+	m, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	terms := andMatcher{m}
+	for p.peek() == "AND" {
+		p.pos++
+		if m, err = p.unary(); err != nil {
+			return nil, err
+		}
+		terms = append(terms, m)
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *exprParser) unary() (Matcher, error) {
+	if p.peek() == "NOT" {
+		p.pos++
+		m, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notMatcher{m}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (Matcher, error) {
+	switch p.peek() {
+	case "(":
+		p.pos++
+		m, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("expected ) before %s", p.describe())
+		}
+		p.pos++
+		return m, nil
+	case "str":
+		p.pos++
+		return newSubstringMatcher(p.tokens[p.pos-1].Value), nil
+	case "re":
+		p.pos++
+		expr := p.tokens[p.pos-1].Value
+		if *ignoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		return regexpMatcher{re}, nil
+	case "word":
+		var words []string
+		for p.peek() == "word" {
+			words = append(words, p.tokens[p.pos].Value)
+			p.pos++
+		}
+		return newSubstringMatcher(strings.Join(words, " ")), nil
+	}
+	return nil, fmt.Errorf("expected a substring, regexp or ( before %s", p.describe())
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSplitVerbDefs(t *testing.T) {
+	tests := map[string][]string{
+		"GET,POST":                         {"GET", "POST"},
+		",GET,,":                           {"GET"},
+		`"a,b" OR c,d`:                     {`"a,b" OR c`, "d"},
+		`(GET OR POST) AND NOT x,y`:        {`(GET OR POST) AND NOT x`, "y"},
+		`~"GET /(a|b),c" AND "\"q,",other`: {`~"GET /(a|b),c" AND "\"q,"`, "other"},
+	}
+	for in, want := range tests {
+		if got := splitVerbDefs(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitVerbDefs(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		match []string
+		skip  []string
+	}{
+		{`"GET /api" AND NOT "healthcheck"`,
+			[]string{"GET /api/users 12"},
+			[]string{"GET /api/healthcheck 1", "POST /api/users 3"}},
+		{`GET /api AND NOT healthcheck`,
+			[]string{"GET /api/users 12"},
+			[]string{"GET /api/healthcheck 1"}},
+		{`(GET OR HEAD) AND ~"/users/[0-9]+ "`,
+			[]string{"HEAD /users/7 1", "GET /users/12 3"},
+			[]string{"GET /users/me 1", "POST /users/7 1"}},
+		{`NOT NOT POST`, []string{"POST /x 1"}, []string{"GET /x 1"}},
+		{`a OR b AND c`, []string{"a", "b c"}, []string{"b", "c"}},
+		{`"say \"hi\""`, []string{`say "hi" 1`}, []string{"say hi 1"}},
+	}
+	for _, tt := range tests {
+		m, err := parseExpr(tt.expr)
+		if err != nil {
+			t.Errorf("parseExpr(%q): %v", tt.expr, err)
+			continue
+		}
+		for _, line := range tt.match {
+			if !m.Match(line) {
+				t.Errorf("%s did not match %q", tt.expr, line)
+			}
+		}
+		for _, line := range tt.skip {
+			if m.Match(line) {
+				t.Errorf("%s matched %q", tt.expr, line)
+			}
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, bad := range []string{`"open`, `(GET`, `GET)`, `AND GET`, `GET OR`, `NOT`, `~"(["`} {
+		if _, err := parseExpr(bad); err == nil {
+			t.Errorf("parseExpr(%q) succeeded", bad)
+		}
+		var cerr *ConfigError
+		if _, err := parseVerbs(bad); !errors.As(err, &cerr) {
+			t.Errorf("parseVerbs(%q) err = %v, want ConfigError", bad, err)
+		}
+	}
+}
+
+func TestParseVerbsMixed(t *testing.T) {
+	verbs, err := parseVerbs(` GET ,"POST" AND NOT dry-run`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{" GET ", `"POST" AND NOT dry-run`}; !reflect.DeepEqual(verbs.Verbs, want) {
+		t.Errorf("Verbs = %q, want %q", verbs.Verbs, want)
+	}
+	// plain verbs keep their surrounding spaces
+	if verbs.Matchers[0].Match("xGET y") || !verbs.Matchers[0].Match("x GET y") {
+		t.Error("plain verb should match as an exact substring")
+	}
+	if verbs.Matchers[1].Match("POST /x dry-run 1") || !verbs.Matchers[1].Match("POST /x 1") {
+		t.Error("expression verb did not apply NOT")
+	}
+}
@@ -0,0 +1,39 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestNamedPipe(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "fifo")
+	if err := syscall.Mkfifo(name, 0o600); err != nil {
+		t.Skip(err)
+	}
+	if !isStream(name) {
+		t.Errorf("%s is not a stream", name)
+	}
+	go func() {
+		w, err := os.OpenFile(name, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		w.Write([]byte("GET 1\n"))
+		w.Write([]byte("GET 2\n"))
+		w.Close()
+	}()
+	r, err := openInput(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != "GET 1\nGET 2\n" {
+		t.Errorf("read %q, %v", got, err)
+	}
+}
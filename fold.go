@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// diacriticBases maps each base letter to the precomposed lowercase Latin
+// letters that fold to it under -ignore-case.
+var diacriticBases = map[rune]string{
+	'a': "àáâãäåāăą",
+	'c': "çćĉċč",
+	'd': "ďđ",
+	'e': "èéêëēĕėęě",
+	'g': "ĝğġģ",
+	'h': "ĥħ",
+	'i': "ìíîïĩīĭįı",
+	'j': "ĵ",
+	'k': "ķ",
+	'l': "ĺļľŀł",
+	'n': "ñńņň",
+	'o': "òóôõöøōŏő",
+	'r': "ŕŗř",
+	's': "śŝşš",
+	't': "ţťŧ",
+	'u': "ùúûüũūŭůűų",
+	'w': "ŵ",
+	'y': "ýÿŷ",
+	'z': "źżž",
+}
+
+var diacriticFold = make(map[rune]rune)
+
+func init() {
+	for base, variants := range diacriticBases {
+		for _, r := range variants {
+			diacriticFold[r] = base
+		}
+	}
+}
+
+// foldRune lowercases r and strips common Latin diacritics.
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if base, ok := diacriticFold[r]; ok {
+		return base
+	}
+	return r
+}
+
+func foldString(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+// foldMatcher matches a pre-folded substring case- and diacritic-
+// insensitively. ASCII lines, the common case, are searched without
+// allocating; other lines are folded first.
+type foldMatcher string
+
+func newFoldMatcher(s string) foldMatcher {
+	return foldMatcher(foldString(s))
+}
+
+func (m foldMatcher) Match(line string) bool {
+	if isASCII(line) {
+		return containsFoldASCII(line, string(m))
+	}
+	return strings.Contains(foldString(line), string(m))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func lowerASCII(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// containsFoldASCII reports whether s contains the lowercase needle,
+// ignoring ASCII case in s.
+func containsFoldASCII(s, needle string) bool {
+	n := len(needle)
+	if n == 0 {
+		return true
+	}
+	first := needle[0]
+	for i := 0; i+n <= len(s); i++ {
+		if lowerASCII(s[i]) != first {
+			continue
+		}
+		j := 1
+		for j < n && lowerASCII(s[i+j]) == needle[j] {
+			j++
+		}
+		if j == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFoldMatcher(t *testing.T) {
+	tests := []struct {
+		needle string
+		line   string
+		want   bool
+	}{
+		{"GET /api", "get /API/users 12", true},
+		{"get", "GeT", true},
+		{"get", "GE", false},
+		{"Café", "order from CAFE 12", true},
+		{"cafe", "commande au Café 12", true},
+		{"Łódź", "city=lodz 3", true},
+		{"naïve", "NAIVE 1", true},
+		{"x", "", false},
+		{"", "anything", true},
+		{"straße", "STRASSE", false},
+	}
+	for _, tt := range tests {
+		if got := newFoldMatcher(tt.needle).Match(tt.line); got != tt.want {
+			t.Errorf("fold %q in %q = %v, want %v", tt.needle, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseVerbsIgnoreCase(t *testing.T) {
+	*ignoreCase = true
+	defer func() { *ignoreCase = false }()
+
+	verbs, err := parseVerbs(`get,"Post" AND NOT ~"dry-RUN"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verbs.Matchers[0].Match("GET /x 1") {
+		t.Error("plain verb did not ignore case")
+	}
+	if !verbs.Matchers[1].Match("POST /x 1") || verbs.Matchers[1].Match("post /x DRY-run 1") {
+		t.Error("expression verb did not ignore case")
+	}
+}
+
+func BenchmarkFoldMatcher(b *testing.B) {
+	line := strings.Repeat("2024-03-01T10:00:00Z host1 ", 4) + "GET /api/users 200 12.5"
+	m := newFoldMatcher("get /API/USERS")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Match(line)
+	}
+}
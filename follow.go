@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// followPoll is how often a followed file is checked for new data.
+var followPoll = 250 * time.Millisecond
+
+// followReader reads a file like tail -F: at its end it waits for more to
+// be written, reopens the file when it is replaced by rotation and starts
+// over when it is truncated in place. Reads return io.EOF once stopped.
+// The file is opened with openShared, so that the writer can rename it
+// also on Windows.
+type followReader struct {
+	name   string
+	f      *os.File
+	offset int64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// openFollow follows the local file name until the process is
+// interrupted, which ends the input and lets the report be printed.
+func openFollow(name string) (io.ReadCloser, error) {
+	r, err := newFollowReader(name)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("following %s, interrupt to report", name)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		signal.Stop(sig)
+		r.Stop()
+	}()
+	return r, nil
+}
+
+func newFollowReader(name string) (*followReader, error) {
+	f, err := openShared(name)
+	if err != nil {
+		return nil, err
+	}
+	return &followReader{name: name, f: f, stop: make(chan struct{})}, nil
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		r.offset += int64(n)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		// at the end of what was written so far
+		if r.reopened() {
+			continue
+		}
+		select {
+		case <-r.stop:
+			return 0, io.EOF
+		case <-time.After(followPoll):
+		}
+	}
+}
+
+// reopened switches to the start of a new file at the name, or of the
+// same one if it was truncated, and reports whether there may be more to
+// read. A replaced file is read to its end before switching.
+func (r *followReader) reopened() bool {
+	fi, err := os.Stat(r.name)
+	if err != nil {
+		// renamed away and not yet recreated
+		return false
+	}
+	cur, err := r.f.Stat()
+	if err != nil {
+		return false
+	}
+	if !os.SameFile(fi, cur) {
+		if cur.Size() > r.offset {
+			// written to just before the rotation
+			return true
+		}
+		f, err := openShared(r.name)
+		if err != nil {
+			return false
+		}
+		log.Printf("%s: rotated, reading the new file", r.name)
+		r.f.Close()
+		r.f, r.offset = f, 0
+		return true
+	}
+	if cur.Size() < r.offset {
+		log.Printf("%s: truncated, reading from the start", r.name)
+		if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+			return false
+		}
+		r.offset = 0
+		return true
+	}
+	return false
+}
+
+// Stop ends the input at the current end of the file.
+func (r *followReader) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+func (r *followReader) Close() error {
+	r.Stop()
+	return r.f.Close()
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// openShared opens name for reading. Open files can be renamed and deleted
+// here anyway.
+func openShared(name string) (*os.File, error) {
+	return os.Open(name)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFollowRotation(t *testing.T) {
+	captureLog(t)
+	defer func(poll time.Duration) { followPoll = poll }(followPoll)
+	followPoll = time.Millisecond
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("a 1\n"), 0644)
+
+	r, err := newFollowReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	lines := make(chan string)
+	go func() {
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+		close(lines)
+	}()
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a line")
+		}
+		return ""
+	}
+	appendTo := func(path, text string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(text)
+		f.Close()
+	}
+
+	var got []string
+	got = append(got, next())
+	appendTo(path, "b 2\n")
+	got = append(got, next())
+
+	// written just before the rename, then the new file
+	appendTo(path, "c 3\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(path, "d 4\n")
+	got = append(got, next(), next())
+
+	// copytruncate
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	appendTo(path, "e 5\n")
+	got = append(got, next())
+
+	r.Stop()
+	if line, ok := <-lines; ok {
+		t.Errorf("read %q after Stop", line)
+	}
+	if want := []string{"a 1", "b 2", "c 3", "d 4", "e 5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lineFormats are the accepted -format values. In text lines fields are
+// separated by whitespace and -verb-key, -time-key and -value-key are
+// 1-based field numbers; in the other formats they are key names.
+var lineFormats = []string{"text", "json", "logfmt", "csv", "nginx", "alb"}
+
+// formatPreset holds the defaults a -format fills in for the options that
+// weren't given.
+type formatPreset struct {
+	VerbKey, TimeKey, ValueKey, StatusKey, InputUnit string
+	// UserAgentKey is the field of the user agent, for -group-by=user-agent
+	UserAgentKey string
+}
+
+// formatPresets are the formats of well-known logs, whose keys don't need
+// to be given.
+var formatPresets = map[string]formatPreset{
+	// the combined log format followed by $request_time, in seconds
+	"nginx": {"request", "time_local", "request_time", "status", "s", "http_user_agent"},
+	// AWS load balancers log absolute URLs, so verbs match "GET /path"
+	"alb": {"route", "time", "target_processing_time", "elb_status_code", "s", "user_agent"},
+}
+
+func init() {
+	flagChoices["format"] = lineFormats
+}
+
+// checkLineFormat checks -format and its keys, after filling in the
+// defaults of a preset format.
+func checkLineFormat() error {
+	if !containsString(lineFormats, *lineFormat) {
+		return &ConfigError{"format", fmt.Errorf("unknown format %q, want one of %s",
+			*lineFormat, strings.Join(lineFormats, ", "))}
+	}
+	if preset, ok := formatPresets[*lineFormat]; ok {
+		setDefault := func(option *string, value string) {
+			if *option == "" {
+				*option = value
+			}
+		}
+		setDefault(verbKey, preset.VerbKey)
+		setDefault(timeKey, preset.TimeKey)
+		setDefault(valueKey, preset.ValueKey)
+		setDefault(statusKey, preset.StatusKey)
+		setDefault(inputUnit, preset.InputUnit)
+	}
+	if *lineFormat == "text" {
+		for option, key := range map[string]string{"verb-key": *verbKey, "time-key": *timeKey, "value-key": *valueKey, "group-key": *groupKey, "status-key": *statusKey, "tenant-key": *tenantKey, "variant-key": *variantKey, "lookup-key": *lookupKey} {
+			if key == "" || lookup != nil && lookup.Labels[key] {
+				continue
+			}
+			if n, err := strconv.Atoi(key); err != nil || n == 0 {
+				return &ConfigError{option, fmt.Errorf("%q is not a field number, as -format=text needs", key)}
+			}
+		}
+		return nil
+	}
+	if *valueKey == "" {
+		return &ConfigError{"value-key", fmt.Errorf("-format=%s needs the key of the value", *lineFormat)}
+	}
+	return nil
+}
+
+// lineField returns the field key of line in -format, or the -lookup label
+// key. obj is the object of the line from decodeLine, or nil to read the
+// field from the text.
+func lineField(line string, obj jsonObject, key string) (string, bool) {
+	if lookup != nil && lookup.Labels[key] {
+		return lookup.Label(line, obj, key)
+	}
+	if obj != nil {
+		return obj.Field(key)
+	}
+	return formatField(*lineFormat, line, key)
+}
+
+// formatField returns the field key of line in format.
+func formatField(format, line, key string) (string, bool) {
+	switch format {
+	case "json":
+		return jsonField(line, key)
+	case "logfmt":
+		return logfmtField(line, key)
+	case "csv":
+		return csvField(line, key)
+	case "nginx":
+		return nginxField(line, key)
+	case "alb":
+		return albField(line, key)
+	}
+	n, _ := strconv.Atoi(key)
+	return nthField(line, n)
+}
+
+// jsonObject is a decoded JSON line. It's read by several goroutines and
+// must not be changed.
+type jsonObject map[string]interface{}
+
+// decodeLine decodes a -format=json line once, so that its fields are read
+// from one decoding: the scanner decodes each line and passes the object
+// down with it. It returns nil for other formats and for lines that aren't
+// JSON objects.
+func decodeLine(line string) jsonObject {
+	if *lineFormat != "json" {
+		return nil
+	}
+	return decodeJSON(line)
+}
+
+func decodeJSON(line string) jsonObject {
+	var obj jsonObject
+	d := json.NewDecoder(strings.NewReader(line))
+	d.UseNumber()
+	if d.Decode(&obj) != nil {
+		return nil
+	}
+	return obj
+}
+
+// jsonField returns the value at key, which may be a dotted path into
+// nested objects, of the JSON object line. Strings are returned without
+// quotes and other values as JSON.
+func jsonField(line, key string) (string, bool) {
+	return decodeJSON(line).Field(key)
+}
+
+// Field returns the value at key in o, as jsonField does.
+func (o jsonObject) Field(key string) (string, bool) {
+	if o == nil {
+		return "", false
+	}
+	var v interface{} = map[string]interface{}(o)
+	for rest, more := key, true; more; {
+		var part string
+		part, rest, more = strings.Cut(rest, ".")
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if v, ok = m[part]; !ok {
+			return "", false
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case nil:
+		return "", false
+	}
+	text, _ := json.Marshal(v)
+	return string(text), true
+}
+
+// verbSubject is the text verbs are matched against: the -verb-key field,
+// or the whole line.
+func verbSubject(line string, obj jsonObject) string {
+	if *verbKey == "" {
+		return line
+	}
+	s, _ := lineField(line, obj, *verbKey)
+	return s
+}
+
+// timeSubject is the text timestamps are detected in: the -time-key field,
+// or the whole line. Numeric fields are taken as Unix seconds, or
+// milliseconds if too large for seconds, and returned as RFC 3339.
+func timeSubject(line string, obj jsonObject) string {
+	if *timeKey == "" {
+		return line
+	}
+	s, _ := lineField(line, obj, *timeKey)
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		if n > 1e11 {
+			n /= 1000
+		}
+		sec := int64(n)
+		return time.Unix(sec, int64((n-float64(sec))*1e9)).UTC().Format(time.RFC3339Nano)
+	}
+	return s
+}
+
+// logfmtField returns the value of key in a logfmt line of key=value pairs
+// separated by spaces, where values may be double-quoted with backslash
+// escapes. A key without =value has the value "true".
+func logfmtField(line, key string) (string, bool) {
+	for i := 0; i < len(line); {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		k := line[start:i]
+		if i == len(line) || line[i] == ' ' {
+			if k == key && k != "" {
+				return "true", true
+			}
+			continue
+		}
+		i++ // '='
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return "", false // unterminated quote
+			}
+			if k == key {
+				v, err := strconv.Unquote(line[i : end+1])
+				return v, err == nil
+			}
+			i = end + 1
+			continue
+		}
+		start = i
+		for i < len(line) && line[i] != ' ' {
+			i++
+		}
+		if k == key {
+			return line[start:i], true
+		}
+	}
+	return "", false
+}
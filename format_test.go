@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJSONField(t *testing.T) {
+	line := `{"ts":"2024-03-01T10:00:00Z","route":"GET /api","http":{"status":200,"duration_ms":12.5},"user":null}`
+	tests := []struct {
+		key, want string
+		ok        bool
+	}{
+		{"route", "GET /api", true},
+		{"http.duration_ms", "12.5", true},
+		{"http.status", "200", true},
+		{"http", `{"duration_ms":12.5,"status":200}`, true},
+		{"user", "", false},
+		{"http.missing", "", false},
+		{"route.x", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := jsonField(line, tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("jsonField(%s) = %q, %v; want %q, %v", tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+	if _, ok := jsonField("GET /api 12", "route"); ok {
+		t.Error("field found in a line that isn't JSON")
+	}
+
+	// the fields of a decoded line are read without decoding it again
+	defer func(format string) { *lineFormat = format }(*lineFormat)
+	*lineFormat = "json"
+	obj := decodeLine(line)
+	if allocs := testing.AllocsPerRun(100, func() {
+		lineField(line, obj, "route")
+		lineField(line, obj, "ts")
+		lineField(line, obj, "http.missing")
+	}); allocs != 0 {
+		t.Errorf("reading the fields of a decoded line allocated %v times, want none", allocs)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	captureLog(t)
+	defer func(format, verb, ts, value string) {
+		*lineFormat, *verbKey, *timeKey, *valueKey = format, verb, ts, value
+	}(*lineFormat, *verbKey, *timeKey, *valueKey)
+	*lineFormat, *verbKey, *timeKey, *valueKey = "json", "route", "ts", "http.duration_ms"
+	if err := checkLineFormat(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte(`{"ts":1709287200,"route":"GET /api","http":{"duration_ms":12.5},"msg":"POST"}
+{"ts":1709287260000,"route":"POST /api","http":{"duration_ms":40}}
+{"ts":1709287320,"route":"GET /api/users","http":{"duration_ms":"7"}}
+`), 0644)
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	since, _ := time.Parse(time.RFC3339, "2024-03-01T10:00:30Z")
+	go filterValues(path, verbs, TimeWindow{Since: since}, c)
+	values := processLines(c, nil)
+	if len(values.Values) != 1 || values.Values[0] != 7 {
+		t.Errorf("Values = %v, want [7]", values.Values)
+	}
+
+	*valueKey = ""
+	if err := checkLineFormat(); err == nil {
+		t.Error("-format=json without -value-key was accepted")
+	}
+	*lineFormat, *valueKey = "text", "route"
+	if err := checkLineFormat(); err == nil {
+		t.Error("-format=text with a key name was accepted")
+	}
+}
+
+func TestLogfmtField(t *testing.T) {
+	line := `ts=2024-03-01T10:00:00Z verb=GET_users latency_ms=12.3 msg="slow \"db\" call" empty= debug path=/a=b`
+	tests := []struct {
+		key, want string
+		ok        bool
+	}{
+		{"ts", "2024-03-01T10:00:00Z", true},
+		{"verb", "GET_users", true},
+		{"latency_ms", "12.3", true},
+		{"msg", `slow "db" call`, true},
+		{"empty", "", true},
+		{"debug", "true", true},
+		{"path", "/a=b", true},
+		{"missing", "", false},
+		{"call", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := logfmtField(line, tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("logfmtField(%s) = %q, %v; want %q, %v", tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+	if _, ok := logfmtField(`msg="unterminated latency_ms=3`, "latency_ms"); ok {
+		t.Error("found a key inside an unterminated quote")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// Gap is a stretch of time without any matched line.
+type Gap struct {
+	From, To time.Time
+}
+
+func (g Gap) Duration() time.Duration { return g.To.Sub(g.From) }
+
+// gapDetector records the gaps longer than min between the timestamps it is
+// given. Timestamps may arrive slightly out of order; a gap is measured from
+// the latest one seen so far.
+type gapDetector struct {
+	min  time.Duration
+	last time.Time
+	Gaps []Gap
+}
+
+func (d *gapDetector) Add(ts time.Time) {
+	if !d.last.IsZero() && ts.Sub(d.last) > d.min {
+		d.Gaps = append(d.Gaps, Gap{d.last, ts})
+	}
+	if ts.After(d.last) {
+		d.last = ts
+	}
+}
+
+func printGaps(gaps []Gap, min time.Duration) {
+	if len(gaps) == 0 {
+		log.Printf("no gaps longer than %v", min)
+		return
+	}
+	summary := fmt.Sprintf("%d gaps longer than %v without matching lines:\n", len(gaps), min)
+	for _, g := range gaps {
+		summary += fmt.Sprintf("  %s to %s  (%v)\n", g.From.Format(time.RFC3339), g.To.Format(time.RFC3339), g.Duration())
+	}
+	log.Print(summary)
+}
+
+func writeMarkdownGaps(w io.Writer, gaps []Gap, min time.Duration) {
+	fmt.Fprintf(w, "\n%d gaps longer than %v without matching lines.\n", len(gaps), min)
+	if len(gaps) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n| from | to | duration |\n|---|---|---:|\n")
+	for _, g := range gaps {
+		fmt.Fprintf(w, "| %s | %s | %v |\n", g.From.Format(time.RFC3339), g.To.Format(time.RFC3339), g.Duration())
+	}
+}
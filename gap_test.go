@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGapDetection(t *testing.T) {
+	captureLog(t)
+	*gapThreshold = time.Minute
+	defer func() { *gapThreshold = 0 }()
+
+	c := make(chan LineMatch, 8)
+	c <- LineMatch{Line: "2024-03-01T10:00:00Z GET /a 10", Verb: "GET"}
+	c <- LineMatch{Line: "2024-03-01T10:00:30Z POST /b 20", Verb: "POST"}
+	c <- LineMatch{Line: "2024-03-01T10:05:00Z GET /c 30", Verb: "GET"}
+	c <- LineMatch{Line: "2024-03-01T10:04:50Z GET /d 40", Verb: "GET"} // out of order
+	c <- LineMatch{Line: "no timestamp GET 50", Verb: "GET"}
+	c <- LineMatch{Line: "2024-03-01T10:06:00Z GET /e 60", Verb: "GET"}
+	c <- LineMatch{Line: "2024-03-01T10:08:00Z GET /f 70", Verb: "GET"}
+	close(c)
+
+	values := processLines(c, nil)
+	if len(values.Values) != 7 {
+		t.Errorf("got %d values, want 7: lines without timestamps still count", len(values.Values))
+	}
+	at := func(s string) time.Time {
+		ts, _ := time.Parse(time.RFC3339, s)
+		return ts
+	}
+	want := []Gap{
+		{at("2024-03-01T10:00:30Z"), at("2024-03-01T10:05:00Z")},
+		{at("2024-03-01T10:06:00Z"), at("2024-03-01T10:08:00Z")},
+	}
+	if !reflect.DeepEqual(values.Gaps, want) {
+		t.Errorf("Gaps = %v, want %v", values.Gaps, want)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// gcloudCommand is the Google Cloud CLI used to list and stream GCS
+// objects, so that its usual credential and project configuration
+// applies.
+var gcloudCommand = "gcloud"
+
+func init() {
+	registerSource(&Source{Scheme: "gs", Expand: expandGCS, Open: openGCS})
+}
+
+// expandGCS lists the objects under a gs://bucket/prefix/ URI, or those
+// matching a glob in its last path element, like expandS3. Any other URI
+// is one object.
+func expandGCS(uri, include string) ([]string, error) {
+	dir, pattern, recursive := uri, "", false
+	switch {
+	case strings.ContainsAny(path.Base(uri), "*?["):
+		dir, pattern = uri[:strings.LastIndexByte(uri, '/')+1], path.Base(uri)
+	case strings.HasSuffix(uri, "/"):
+		pattern, recursive = include, true
+	default:
+		return []string{uri}, nil
+	}
+	out, err := commandOutput(gcloudCommand, "storage", "ls", dir+"**")
+	if err != nil {
+		return nil, err
+	}
+	var objects []string
+	for _, object := range strings.Split(string(out), "\n") {
+		// one gs://bucket/key per line
+		object = strings.TrimSpace(object)
+		if !strings.HasPrefix(object, dir) || strings.HasSuffix(object, "/") {
+			continue
+		}
+		// a glob only matches directly below its prefix, like a local one
+		if !recursive && strings.Contains(strings.TrimPrefix(object, dir), "/") {
+			continue
+		}
+		if ok, _ := path.Match(pattern, path.Base(object)); ok {
+			objects = append(objects, object)
+		}
+	}
+	if len(objects) == 0 {
+		return nil, &ConfigError{"input", fmt.Errorf("no objects match %s", uri)}
+	}
+	return objects, nil
+}
+
+func openGCS(uri string) (io.ReadCloser, error) {
+	return startCommand(gcloudCommand, "storage", "cat", uri)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGCSSource(t *testing.T) {
+	fakeCommand(t, &gcloudCommand, `
+case "$2" in
+ls) [ "$3" = 'gs://bucket/logs/**' ] || exit 1
+    cat <<'LIST'
+gs://bucket/logs/2024-03-01/app.log
+gs://bucket/logs/2024-03-01/app 2.log.gz
+gs://bucket/logs/app.log
+gs://bucket/logs/notes.txt
+LIST
+;;
+cat) [ "$3" = gs://bucket/logs/app.log ] || { echo "no such object: $3" >&2; exit 1; }
+    cat testdata/access.log ;;
+esac
+`)
+	tests := []struct {
+		uri, include string
+		want         []string
+	}{
+		{"gs://bucket/logs/", "*.log*", []string{
+			"gs://bucket/logs/2024-03-01/app.log", "gs://bucket/logs/2024-03-01/app 2.log.gz", "gs://bucket/logs/app.log"}},
+		{"gs://bucket/logs/*.txt", "*", []string{"gs://bucket/logs/notes.txt"}},
+		{"gs://bucket/logs/app.log", "*", []string{"gs://bucket/logs/app.log"}},
+	}
+	for _, tt := range tests {
+		got, err := expandInputs([]string{tt.uri}, tt.include)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandInputs(%s) = %q, %v; want %q", tt.uri, got, err, tt.want)
+		}
+	}
+	if _, err := expandInputs([]string{"gs://bucket/logs/*.csv"}, "*"); err == nil {
+		t.Error("glob without matching objects was accepted")
+	}
+
+	captureLog(t)
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("gs://bucket/logs/app.log", verbs, TimeWindow{}, c)
+	if values := processLines(c, nil); len(values.Values) != 4 {
+		t.Errorf("read %d GET values from GCS, want 4", len(values.Values))
+	}
+
+	r, err := openInput("gs://bucket/missing.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Read(make([]byte, 1))
+	if err := r.Close(); err == nil {
+		t.Error("failed download was not reported")
+	}
+}
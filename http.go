@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+func init() {
+	registerSource(&Source{Scheme: "http", Open: openHTTP})
+	registerSource(&Source{Scheme: "https", Open: openHTTP})
+}
+
+// openHTTP streams the body of a GET of uri. Only a 2xx response is read;
+// anything else is an error naming the status.
+func openHTTP(uri string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "metrics/"+version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", uri, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestHTTPSource(t *testing.T) {
+	captureLog(t)
+	plain, err := os.ReadFile("testdata/access.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/access.log":
+			w.Write(plain)
+		case "/access.log.gz":
+			zw := gzip.NewWriter(w)
+			zw.Write(plain)
+			zw.Close()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	verbs, _ := parseVerbs("GET")
+	for _, path := range []string{"/access.log", "/access.log.gz"} {
+		c := make(chan LineMatch, ChanSize)
+		go filterValues(srv.URL+path, verbs, TimeWindow{}, c)
+		if values := processLines(c, nil); len(values.Values) != 4 {
+			t.Errorf("%s: read %d GET values, want 4", path, len(values.Values))
+		}
+	}
+	if _, err := openInput(srv.URL + "/missing.log"); err == nil {
+		t.Error("404 was not reported")
+	}
+}
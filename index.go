@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// A sidecar index records, for every block of roughly indexBlockSize bytes
+// of a log, the offset of its first line and the earliest and latest
+// timestamps in it. Seeking to the first block whose latest timestamp is
+// not before -since is correct even if the log is not sorted.
+
+const (
+	indexBlockSize = 1 << 20
+	indexHeadSize  = 4096
+	indexMagic     = "metrics-index 1"
+)
+
+type IndexBlock struct {
+	Offset   int64
+	Min, Max time.Time
+}
+
+type FileIndex struct {
+	Size    int64
+	ModTime time.Time
+	Head    string // hash of the first indexHeadSize bytes
+	Blocks  []IndexBlock
+}
+
+func indexPath(filename string) string {
+	return filename + ".idx"
+}
+
+func headHash(f *os.File) (string, error) {
+	return hashPrefix(f, indexHeadSize)
+}
+
+// loadIndex reads the sidecar of f. It is usable if f has the same head and
+// was only appended to since the index was written.
+func loadIndex(filename string, f *os.File) (*FileIndex, bool) {
+	sidecar, err := os.Open(indexPath(filename))
+	if err != nil {
+		return nil, false
+	}
+	defer sidecar.Close()
+
+	var idx FileIndex
+	var modTime int64
+	r := bufio.NewReader(sidecar)
+	var magic1, magic2 string
+	if _, err := fmt.Fscanf(r, "%s %s\n", &magic1, &magic2); err != nil || magic1+" "+magic2 != indexMagic {
+		return nil, false
+	}
+	if _, err := fmt.Fscanf(r, "size %d mtime %d head %s\n", &idx.Size, &modTime, &idx.Head); err != nil {
+		return nil, false
+	}
+	idx.ModTime = time.Unix(0, modTime)
+	for {
+		var b IndexBlock
+		var min, max int64
+		if _, err := fmt.Fscanf(r, "%d %d %d\n", &b.Offset, &min, &max); err != nil {
+			break
+		}
+		b.Min, b.Max = time.Unix(0, min).UTC(), time.Unix(0, max).UTC()
+		idx.Blocks = append(idx.Blocks, b)
+	}
+
+	fi, err := f.Stat()
+	if err != nil || fi.Size() < idx.Size || (fi.Size() == idx.Size && !fi.ModTime().Equal(idx.ModTime)) {
+		return nil, false
+	}
+	if head, err := headHash(f); err != nil || head != idx.Head {
+		return nil, false
+	}
+	return &idx, true
+}
+
+// SeekOffset returns the offset of the first block that may hold lines at
+// or after since.
+func (idx *FileIndex) SeekOffset(since time.Time) int64 {
+	for _, b := range idx.Blocks {
+		if !b.Max.Before(since) {
+			return b.Offset
+		}
+	}
+	return idx.Size
+}
+
+func (idx *FileIndex) write(filename string) error {
+	tmp := indexPath(filename) + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "%s\nsize %d mtime %d head %s\n", indexMagic, idx.Size, idx.ModTime.UnixNano(), idx.Head)
+	for _, b := range idx.Blocks {
+		fmt.Fprintf(w, "%d %d %d\n", b.Offset, b.Min.UnixNano(), b.Max.UnixNano())
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, indexPath(filename))
+}
+
+// indexBuilder collects blocks while a file is read from the start.
+type indexBuilder struct {
+	blocks []IndexBlock
+	cur    *IndexBlock
+}
+
+// Add records a line starting at offset. Lines without a timestamp only
+// open blocks.
+func (b *indexBuilder) Add(offset int64, ts time.Time, ok bool) {
+	if b.cur == nil || offset-b.cur.Offset >= indexBlockSize {
+		b.blocks = append(b.blocks, IndexBlock{Offset: offset})
+		b.cur = &b.blocks[len(b.blocks)-1]
+	}
+	if !ok {
+		return
+	}
+	if b.cur.Min.IsZero() || ts.Before(b.cur.Min) {
+		b.cur.Min = ts
+	}
+	if ts.After(b.cur.Max) {
+		b.cur.Max = ts
+	}
+}
+
+// Finish writes the sidecar for f, which must have been read to the end.
+func (b *indexBuilder) Finish(filename string, f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	head, err := headHash(f)
+	if err != nil {
+		return err
+	}
+	// a block without timestamps can't be skipped: give it an open range
+	for i := range b.blocks {
+		if b.blocks[i].Max.IsZero() {
+			b.blocks[i].Max = time.Unix(0, math.MaxInt64).UTC()
+		}
+	}
+	idx := FileIndex{Size: fi.Size(), ModTime: fi.ModTime(), Head: head, Blocks: b.blocks}
+	return idx.write(filename)
+}
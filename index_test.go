@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIndexSidecar(t *testing.T) {
+	*useIndex = true
+	defer func() { *useIndex = false }()
+
+	path := writeSortedLog(t, 60000) // about 3MB, so several blocks
+	verbs, _ := parseVerbs("GET")
+	count := func(window TimeWindow) int {
+		c := make(chan LineMatch, ChanSize)
+		go filterValues(path, verbs, window, c)
+		n := 0
+		for range c {
+			n++
+		}
+		return n
+	}
+
+	if n := count(TimeWindow{}); n != 60000 {
+		t.Fatalf("first pass matched %d lines, want 60000", n)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	idx, ok := loadIndex(path, f)
+	if !ok || len(idx.Blocks) < 3 {
+		t.Fatalf("index not written or too small: %v %+v", ok, idx)
+	}
+	for i, b := range idx.Blocks {
+		if b.Min.After(b.Max) || (i > 0 && b.Offset <= idx.Blocks[i-1].Offset) {
+			t.Errorf("bad block %d: %+v", i, b)
+		}
+	}
+
+	since := windowBase.Add(50000 * time.Second)
+	offset := idx.SeekOffset(since)
+	if offset == 0 || offset >= idx.Size {
+		t.Errorf("SeekOffset(+50000s) = %d, want inside the file", offset)
+	}
+	if n := count(TimeWindow{Since: since}); n != 10000 {
+		t.Errorf("indexed run matched %d lines, want 10000", n)
+	}
+
+	// appending keeps the index usable; rewriting the head invalidates it
+	af, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	af.WriteString("2024-03-02T00:00:00Z host GET /x 200 1\n")
+	af.Close()
+	if _, ok := loadIndex(path, f); !ok {
+		t.Error("index rejected after an append")
+	}
+	if n := count(TimeWindow{Since: since}); n != 10001 {
+		t.Errorf("run after append matched %d lines, want 10001", n)
+	}
+	data, _ := os.ReadFile(path)
+	data[0] = '1'
+	os.WriteFile(path, data, 0644)
+	if _, ok := loadIndex(path, f); ok {
+		t.Error("index accepted after the head changed")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// expandInputs expands the glob patterns among args, each pattern into its
+// matches in lexical order, and walks the directories among them for files
+// whose name matches include. Tar archives are replaced by their members
+// matching include. Other names are kept as they are, so a missing file is
+// reported when it is opened.
+func expandInputs(args []string, include string) ([]string, error) {
+	if _, err := filepath.Match(include, ""); err != nil {
+		return nil, &ConfigError{"include", err}
+	}
+	var files []string
+	stdin := false
+	for _, arg := range args {
+		if arg == "-" {
+			if stdin {
+				return nil, &ConfigError{"input", errors.New("stdin (-) given more than once")}
+			}
+			stdin = true
+		}
+		if src := sourceFor(arg); src != nil {
+			if src.Expand == nil {
+				files = append(files, arg)
+				continue
+			}
+			found, err := src.Expand(arg, include)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, found...)
+			continue
+		}
+		if fi, err := os.Stat(arg); err == nil && fi.IsDir() {
+			found, err := walkInputs(arg, include)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, found...)
+			continue
+		}
+		if !strings.ContainsAny(arg, "*?[") {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, &ConfigError{"input", fmt.Errorf("%s: %v", arg, err)}
+		}
+		if len(matches) == 0 {
+			return nil, &ConfigError{"input", fmt.Errorf("no files match %s", arg)}
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	// the members of local tar archives are inputs of their own
+	var inputs []string
+	for _, file := range files {
+		if file == "-" || sourceFor(file) != nil || !isArchive(file) {
+			inputs = append(inputs, file)
+			continue
+		}
+		members, err := expandTar("tar://"+file, include)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, members...)
+	}
+	return inputs, nil
+}
+
+// regionPattern matches cloud region names such as us-east-1 or
+// europe-west4.
+var regionPattern = regexp.MustCompile(`^[a-z]{2,}(-[a-z]+)+-?[0-9]+$`)
+
+// trailingRegion splits a region name given after the inputs, as in
+// "metrics GET 'logs/*.log' eu-west-1", off args, unless a local file has
+// that name. The region only labels the run; it is not an input.
+func trailingRegion(args []string) ([]string, string) {
+	if len(args) < 2 {
+		return args, ""
+	}
+	last := args[len(args)-1]
+	if !regionPattern.MatchString(last) {
+		return args, ""
+	}
+	if _, err := os.Stat(last); err == nil {
+		return args, ""
+	}
+	return args[:len(args)-1], last
+}
+
+// walkInputs returns the files below dir whose base name matches include,
+// in lexical order, leaving out -index sidecars.
+func walkInputs(dir, include string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(path, ".idx") {
+			return nil
+		}
+		if ok, _ := filepath.Match(include, d.Name()); ok {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, &ConfigError{"input", fmt.Errorf("no files in %s match -include %q", dir, include)}
+	}
+	return files, nil
+}
+
+var groupByFile = &Grouping{
+	Label: "file",
+	Keys: func(dst []string, m LineMatch) []string {
+		return append(dst, m.File)
+	},
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"frontend-2.log", "frontend-1.log", "backend.log"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	got, err := expandInputs([]string{filepath.Join(dir, "frontend-*.log"), "-", "missing.log"}, "*")
+	want := []string{filepath.Join(dir, "frontend-1.log"), filepath.Join(dir, "frontend-2.log"), "-", "missing.log"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("expandInputs = %v, %v; want %v", got, err, want)
+	}
+
+	for _, args := range [][]string{{filepath.Join(dir, "*.gz")}, {"-", "-"}, {"[.log"}} {
+		if _, err := expandInputs(args, "*"); err == nil {
+			t.Errorf("expandInputs(%q) succeeded", args)
+		}
+	}
+}
+
+func TestExpandInputsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"2024-03-02/app.log", "2024-03-01/app.log.gz", "2024-03-01/app.log", "2024-03-01/app.log.idx", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, nil, 0644)
+	}
+	got, err := expandInputs([]string{dir}, "*.log*")
+	want := []string{
+		filepath.Join(dir, "2024-03-01/app.log"),
+		filepath.Join(dir, "2024-03-01/app.log.gz"),
+		filepath.Join(dir, "2024-03-02/app.log"),
+	}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("expandInputs(dir) = %v, %v; want %v", got, err, want)
+	}
+	if _, err := expandInputs([]string{dir}, "*.csv"); err == nil {
+		t.Error("directory without matching files was accepted")
+	}
+	if _, err := expandInputs([]string{dir}, "["); err == nil {
+		t.Error("bad -include pattern was accepted")
+	}
+}
+
+func TestGroupByFile(t *testing.T) {
+	captureLog(t)
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	os.WriteFile(a, []byte("GET /x 10\nGET /y 20\n"), 0644)
+	os.WriteFile(b, []byte("GET /z 30\nPOST /z 40\n"), 0644)
+
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	go filterFiles([]string{a, b}, verbs, TimeWindow{}, c)
+	values := processLines(c, groupByFile)
+	if want := (Float32Slice{10, 20, 30}); !reflect.DeepEqual(values.Values, want) {
+		t.Errorf("Values = %v, want %v", values.Values, want)
+	}
+	var files []string
+	for name := range values.Groups {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	if !reflect.DeepEqual(files, []string{a, b}) || len(values.Groups[a].Values) != 2 {
+		t.Errorf("Groups = %v", values.Groups)
+	}
+}
+
+func TestTrailingRegion(t *testing.T) {
+	tests := []struct {
+		args   []string
+		inputs []string
+		region string
+	}{
+		{[]string{"logs/frontend-*.log", "eu-west-1"}, []string{"logs/frontend-*.log"}, "eu-west-1"},
+		{[]string{"-", "us-east-1"}, []string{"-"}, "us-east-1"},
+		{[]string{"a.log", "europe-west4"}, []string{"a.log"}, "europe-west4"},
+		{[]string{"eu-west-1"}, []string{"eu-west-1"}, ""},
+		{[]string{"a.log", "b.log"}, []string{"a.log", "b.log"}, ""},
+		{[]string{"a.log", "access-1"}, []string{"a.log", "access-1"}, ""},
+	}
+	for _, tt := range tests {
+		inputs, region := trailingRegion(tt.args)
+		if !reflect.DeepEqual(inputs, tt.inputs) || region != tt.region {
+			t.Errorf("trailingRegion(%q) = %q, %q; want %q, %q", tt.args, inputs, region, tt.inputs, tt.region)
+		}
+	}
+}
+
+// TestMainHelper runs main with the arguments after "--", for runMetrics.
+func TestIsInputArg(t *testing.T) {
+	defer func(f bool) { *follow = f }(*follow)
+	*follow = true // -config -follow GET,POST app.log takes GET,POST as verbs
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, nil, 0644)
+	for name, want := range map[string]bool{
+		"GET,POST":  false,
+		path:        true,
+		"-":         true,
+		"s3://b/k":  true,
+		"logs/*.gz": true,
+	} {
+		if got := isInputArg(name); got != want {
+			t.Errorf("isInputArg(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMainHelper(t *testing.T) {
+	if os.Getenv("METRICS_MAIN") == "" {
+		t.Skip("run by runMetrics")
+	}
+	for i, arg := range os.Args {
+		if arg == "--" {
+			os.Args = append([]string{"metrics"}, os.Args[i+1:]...)
+			break
+		}
+	}
+	main()
+}
+
+// runMetrics runs "metrics args..." in dir with stdin, returning what it
+// logged, and fails the test if it exits with an error.
+func runMetrics(t *testing.T, dir, stdin string, args ...string) string {
+	t.Helper()
+	out, err := tryMetrics(t, dir, stdin, args...)
+	if err != nil {
+		t.Fatalf("metrics %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+// tryMetrics runs "metrics args..." like runMetrics, also returning how it
+// exited.
+func tryMetrics(t *testing.T, dir, stdin string, args ...string) (string, error) {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(exe, append([]string{"-test.run=^TestMainHelper$", "--"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "METRICS_MAIN=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestRegionArgument(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "logs"), 0755)
+	os.WriteFile(filepath.Join(dir, "logs", "frontend-1.log"), []byte("GET /a 10\nPOST /b 20\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "logs", "frontend-2.log"), []byte("GET /c 30\n"), 0644)
+
+	// metrics GET 'logs/frontend-*.log' eu-west-1
+	out := runMetrics(t, dir, "", "GET", "logs/frontend-*.log", "eu-west-1")
+	if !strings.Contains(out, "in eu-west-1") || !strings.Contains(out, "count: 2,") {
+		t.Errorf("glob with a region:\n%s", out)
+	}
+	// zcat foo.gz | metrics GET,POST - us-east-1
+	out = runMetrics(t, dir, "GET /a 10\nPOST /b 20\nPUT /c 5\n", "GET,POST", "-", "us-east-1")
+	if !strings.Contains(out, "in us-east-1") || !strings.Contains(out, "count: 2,") {
+		t.Errorf("stdin with a region:\n%s", out)
+	}
+}
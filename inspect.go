@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommand(&Command{
+		Name:  "inspect",
+		Args:  "[-n lines] <file>",
+		Short: "guess the layout of an unfamiliar log and suggest a command line",
+		Run:   runInspect,
+	})
+}
+
+func runInspect(args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	n := fs.Int("n", 1000, "number of lines to sample")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		commandUsage(commands["inspect"])
+	}
+
+	f, err := openInput(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	r, err := decompress(sniffCompression(f))
+	if err != nil {
+		log.Fatal(err)
+	}
+	lines, err := sampleLines(r, *n)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(lines) == 0 {
+		log.Fatalf("%s is empty", fs.Arg(0))
+	}
+	writeInspection(os.Stdout, fs.Arg(0), inspectLines(lines))
+}
+
+func sampleLines(r io.Reader, n int) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, BuffSize), BuffSize)
+	for len(lines) < n && scanner.Scan() {
+		if line := scanner.Text(); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// FieldInfo describes one whitespace-separated field position across the
+// sampled lines.
+type FieldInfo struct {
+	Index    int // 1-based, as used by -size-field
+	Type     string
+	Example  string
+	Distinct int
+	Min, Max float64
+	values   map[string]int
+}
+
+type Inspection struct {
+	Lines     int
+	Fields    []*FieldInfo
+	Common    int // lines having the most common number of fields
+	Timestamp *TimestampFormat
+	Value     *FieldInfo
+	Verbs     []string
+}
+
+var httpMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true, "HEAD": true, "OPTIONS": true,
+}
+
+func inspectLines(lines []string) Inspection {
+	// only lines with the most common field count are used to type fields
+	byCount := make(map[int][]string)
+	for _, line := range lines {
+		n := len(strings.Fields(line))
+		byCount[n] = append(byCount[n], line)
+	}
+	var width int
+	for n, ls := range byCount {
+		if len(ls) > len(byCount[width]) || (len(ls) == len(byCount[width]) && n > width) {
+			width = n
+		}
+	}
+	sample := byCount[width]
+	result := Inspection{Lines: len(lines), Common: len(sample)}
+
+	tsFields := make(map[int]bool)
+	if format, ok := detectTimestampFormat(sample[0]); ok {
+		result.Timestamp = &format
+		for i := format.Field; i < format.Field+format.Span; i++ {
+			tsFields[i] = true
+		}
+	}
+
+	for i := 0; i < width; i++ {
+		info := &FieldInfo{Index: i + 1, values: make(map[string]int)}
+		types := make(map[string]int)
+		for _, line := range sample {
+			field := strings.Fields(line)[i]
+			info.values[field]++
+			if info.Example == "" && field != "-" {
+				info.Example = field
+			}
+			t := fieldType(field)
+			if tsFields[i] {
+				t = "timestamp"
+			}
+			types[t]++
+			if t == "int" || t == "float" {
+				v, _ := strconv.ParseFloat(field, 64)
+				if types["int"]+types["float"] == 1 || v < info.Min {
+					info.Min = v
+				}
+				if types["int"]+types["float"] == 1 || v > info.Max {
+					info.Max = v
+				}
+			}
+		}
+		delete(types, "null")
+		info.Type = "null"
+		for t, c := range types {
+			if c > types[info.Type] || (c == types[info.Type] && t < info.Type) {
+				info.Type = t
+			}
+		}
+		// integers mixed with a few decimals are still floats
+		if info.Type == "int" && types["float"] > 0 {
+			info.Type = "float"
+		}
+		info.Distinct = len(info.values)
+		result.Fields = append(result.Fields, info)
+	}
+
+	// the value is read from the last field; prefer it if it is numeric,
+	// otherwise point at the last float-looking field
+	for i := len(result.Fields) - 1; i >= 0; i-- {
+		if t := result.Fields[i].Type; t == "float" || (t == "int" && i == len(result.Fields)-1) {
+			result.Value = result.Fields[i]
+			break
+		}
+	}
+
+	for _, info := range result.Fields {
+		if info.Type != "word" {
+			continue
+		}
+		var methods []string
+		for v := range info.values {
+			if httpMethods[strings.Trim(v, `"`)] {
+				methods = append(methods, strings.Trim(v, `"`))
+			}
+		}
+		if len(methods) > 0 {
+			sort.Strings(methods)
+			result.Verbs = methods
+			break
+		}
+	}
+	return result
+}
+
+func fieldType(field string) string {
+	switch {
+	case field == "-":
+		return "null"
+	case strings.HasPrefix(field, "/"):
+		return "path"
+	case strings.HasPrefix(field, `"`):
+		return "word"
+	}
+	if _, err := strconv.ParseInt(field, 10, 64); err == nil {
+		return "int"
+	}
+	if _, err := strconv.ParseFloat(field, 64); err == nil {
+		return "float"
+	}
+	if _, err := netip.ParseAddr(field); err == nil {
+		return "ip"
+	}
+	if _, err := netip.ParseAddrPort(field); err == nil {
+		return "ip"
+	}
+	return "word"
+}
+
+func writeInspection(w io.Writer, filename string, in Inspection) {
+	fmt.Fprintf(w, "sampled %d lines, %d of them with %d fields\n\n", in.Lines, in.Common, len(in.Fields))
+	fmt.Fprintf(w, "%-5s  %-9s  %-8s  %s\n", "field", "type", "distinct", "example")
+	for _, f := range in.Fields {
+		example := f.Example
+		if len(example) > 40 {
+			example = example[:37] + "..."
+		}
+		fmt.Fprintf(w, "%-5d  %-9s  %-8d  %s\n", f.Index, f.Type, f.Distinct, example)
+	}
+	fmt.Fprintln(w)
+
+	if in.Timestamp != nil {
+		fmt.Fprintf(w, "timestamp: field %d, layout %q\n", in.Timestamp.Field+1, in.Timestamp.Layout)
+	} else {
+		fmt.Fprintln(w, "timestamp: none found")
+	}
+	last := len(in.Fields)
+	switch {
+	case in.Value == nil:
+		fmt.Fprintln(w, "value: no numeric field found")
+	case in.Value.Index == last:
+		fmt.Fprintf(w, "value: field %d (last), %s from %g to %g\n", in.Value.Index, in.Value.Type, in.Value.Min, in.Value.Max)
+	default:
+		fmt.Fprintf(w, "value: field %d looks like a latency, but metrics reads the last field (%d);\n"+
+			"       reorder the fields before running metrics\n", in.Value.Index, last)
+	}
+
+	verbs := "GET"
+	if len(in.Verbs) > 0 {
+		verbs = strings.Join(in.Verbs, ",")
+	}
+	fmt.Fprintf(w, "\nsuggested command:\n  metrics -breakdown %s %s\n", verbs, filename)
+	if in.Value != nil {
+		for _, f := range in.Fields {
+			if f.Type == "int" && f != in.Value && f.Min >= 0 && f.Max > 1000 {
+				fmt.Fprintf(w, "  metrics -group-by=size -size-field=%d %s %s   # if field %d is a byte count\n",
+					f.Index, verbs, filename, f.Index)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestInspectGolden(t *testing.T) {
+	f, err := os.Open("testdata/access.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines, err := sampleLines(f, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	writeInspection(&buf, "access.log", inspectLines(lines))
+	checkGolden(t, "inspect.golden", buf.Bytes())
+}
+
+func TestFieldType(t *testing.T) {
+	tests := map[string]string{
+		"-":           "null",
+		"/api/users":  "path",
+		"200":         "int",
+		"12.5":        "float",
+		"10.0.0.1":    "ip",
+		"[::1]:8080":  "ip",
+		"GET":         "word",
+		`"GET`:        "word",
+		"host-1.prod": "word",
+	}
+	for in, want := range tests {
+		if got := fieldType(in); got != want {
+			t.Errorf("fieldType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// joinComponents are the breakdown rows of -join, in order: the input's
+// value, the second log's value for the same request, and the difference,
+// e.g. total, app and edge time.
+var joinComponents = []string{"total", "joined", "rest"}
+
+// JoinLog holds the values of a second log by request ID, for -join.
+type JoinLog struct {
+	Values map[string]float32
+	// Matched records the IDs that were joined to an input line.
+	Matched map[string]bool
+}
+
+// joinLog is the -join log, or nil.
+var joinLog *JoinLog
+
+// joinField returns the field key of a line of the -join log, which is in
+// -join-format.
+func joinField(line, key string) (string, bool) {
+	return formatField(*joinFormat, line, key)
+}
+
+// readJoinLog reads the ID and value of every line of the log at path into
+// memory, up to -join-max-ids IDs. Lines without either are skipped; of
+// lines with the same ID, the last one counts.
+func readJoinLog(path string, scale float32) (*JoinLog, error) {
+	var rc io.ReadCloser
+	var err error
+	if src := sourceFor(path); src != nil {
+		rc, err = src.Open(path)
+	} else {
+		rc, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, &ConfigError{"join", err}
+	}
+	defer rc.Close()
+	input, err := decompress(sniffCompression(rc))
+	if err != nil {
+		return nil, &ConfigError{"join", fmt.Errorf("%s: %v", path, err)}
+	}
+
+	j := &JoinLog{Values: make(map[string]float32), Matched: make(map[string]bool)}
+	skipped := 0
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, BuffSize), BuffSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		id, ok := joinField(line, *joinIDKey)
+		var value string
+		if *joinValueKey != "" {
+			value, _ = joinField(line, *joinValueKey)
+		} else {
+			value = line[strings.LastIndexByte(line, ' ')+1:]
+		}
+		v, err := strconv.ParseFloat(value, 32)
+		if !ok || id == "" || err != nil {
+			skipped++
+			continue
+		}
+		j.Values[id] = float32(v) * scale
+		if *joinMaxIDs > 0 && len(j.Values) > *joinMaxIDs {
+			return nil, &ConfigError{"join-max-ids", fmt.Errorf("%s has more than %d request IDs to hold in memory", path, *joinMaxIDs)}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	if skipped > 0 {
+		log.Printf("%s: skipped %d lines without a request ID or value", path, skipped)
+	}
+	return j, nil
+}
+
+// Match returns the joined value for the request of an input line.
+func (j *JoinLog) Match(line string, obj jsonObject) (float32, bool) {
+	id, ok := lineField(line, obj, *joinKey)
+	if !ok {
+		return 0, false
+	}
+	v, ok := j.Values[id]
+	if ok {
+		j.Matched[id] = true
+	}
+	return v, ok
+}
+
+// joinRows returns the percentiles of the -join components in order.
+func joinRows(values AggregatedValues, percentiles []int) []GroupPercentiles {
+	var rows []GroupPercentiles
+	for _, name := range joinComponents {
+		group := values.Joined[name]
+		if group == nil {
+			group = &AggregatedValues{}
+		}
+		rows = append(rows, GroupPercentiles{name, computePercentiles(*group, percentiles)})
+	}
+	return rows
+}
+
+// joinSummary describes how many input lines, estimated for all lines with
+// -sample, and join log entries were matched.
+func joinSummary(values AggregatedValues, j *JoinLog) string {
+	joined := 0
+	if total := values.Joined["total"]; total != nil {
+		joined = len(total.Values)
+	}
+	lines := joined + values.Unjoined
+	unmatched := float32(0)
+	if lines > 0 {
+		unmatched = float32(values.Unjoined) / float32(lines)
+	}
+	return fmt.Sprintf("%d of %d lines joined by request ID (%s unmatched), %d of %d entries of the joined log unmatched",
+		scaleCount(joined), scaleCount(lines), formatShare(unmatched), len(j.Values)-len(j.Matched), len(j.Values))
+}
+
+func printJoin(values AggregatedValues, j *JoinLog, percentiles []int) {
+	printGroups("component", joinRows(values, percentiles), percentiles)
+	log.Print(joinSummary(values, j))
+}
+
+func writeMarkdownJoin(w io.Writer, values AggregatedValues, j *JoinLog, percentiles []int) {
+	fmt.Fprintln(w)
+	writeMarkdownGroups(w, "component", joinRows(values, percentiles), percentiles)
+	fmt.Fprintf(w, "\n%s.\n", joinSummary(values, j))
+}
+
+// loadJoinLog checks the -join options, filling in their defaults, and
+// reads the log.
+func loadJoinLog() (*JoinLog, error) {
+	if *joinKey == "" {
+		return nil, &ConfigError{"join-key", errors.New("-join needs the field of the request ID")}
+	}
+	if *joinFormat == "" {
+		*joinFormat = *lineFormat
+	}
+	if !containsString(lineFormats, *joinFormat) || *joinFormat == "csv" {
+		return nil, &ConfigError{"join-format", fmt.Errorf("can't join %q logs", *joinFormat)}
+	}
+	if *joinIDKey == "" {
+		*joinIDKey = *joinKey
+	}
+	unit := *joinUnit
+	if preset, ok := formatPresets[*joinFormat]; ok {
+		if *joinValueKey == "" {
+			*joinValueKey = preset.ValueKey
+		}
+		if unit == "" {
+			unit = preset.InputUnit
+		}
+	}
+	if unit == "" && *joinFormat == *lineFormat {
+		unit = *inputUnit
+	}
+	scale, ok := inputUnits[unit]
+	if unit == "" {
+		scale, ok = 1, true
+	}
+	if !ok {
+		return nil, &ConfigError{"join-unit", fmt.Errorf("unknown unit %q, want ns, us, ms or s", unit)}
+	}
+	return readJoinLog(*joinFile, scale)
+}
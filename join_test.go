@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJoin(t *testing.T) {
+	buf := captureLog(t)
+	dir := t.TempDir()
+	edge := filepath.Join(dir, "edge.log")
+	os.WriteFile(edge, []byte(`2024-03-01T10:00:00Z r1 GET /api 0.050
+2024-03-01T10:00:01Z r2 GET /api 0.020
+2024-03-01T10:00:02Z r3 GET /api 0.030
+`), 0644)
+	app := filepath.Join(dir, "app.log")
+	os.WriteFile(app, []byte(`{"id":"r1","ms":30}
+{"id":"r2","ms":15}
+{"id":"r9","ms":1}
+not json
+`), 0644)
+
+	defer func(file, key, format, idKey, valueKey, unit string) {
+		*joinFile, *joinKey, *joinFormat, *joinIDKey, *joinValueKey, *joinUnit = file, key, format, idKey, valueKey, unit
+		joinLog, valueScale = nil, 1
+	}(*joinFile, *joinKey, *joinFormat, *joinIDKey, *joinValueKey, *joinUnit)
+	*joinFile, *joinKey, *joinFormat, *joinIDKey, *joinValueKey = app, "2", "json", "id", "ms"
+	var err error
+	if joinLog, err = loadJoinLog(); err != nil {
+		t.Fatal(err)
+	}
+	if len(joinLog.Values) != 3 {
+		t.Errorf("read %d join entries, want 3", len(joinLog.Values))
+	}
+	valueScale = 1000
+
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues(edge, verbs, TimeWindow{}, c)
+	values := processLines(c, nil)
+	rows := joinRows(values, []int{50, 100})
+	for i, want := range []float32{50, 30, 20} {
+		if got := rows[i].Max; got != want {
+			t.Errorf("max %s = %v, want %v", rows[i].Name, got, want)
+		}
+	}
+
+	buf.Reset()
+	printJoin(values, joinLog, []int{50})
+	if want := "2 of 3 lines joined by request ID (33.33% unmatched), 1 of 3 entries of the joined log unmatched"; !strings.Contains(buf.String(), want) {
+		t.Errorf("printJoin logged:\n%s\nwant %q", buf, want)
+	}
+
+	defer func(n int) { *joinMaxIDs = n }(*joinMaxIDs)
+	*joinMaxIDs = 2
+	if _, err := loadJoinLog(); err == nil || !strings.Contains(err.Error(), "more than 2 request IDs") {
+		t.Errorf("loading 3 IDs with -join-max-ids=2: err = %v", err)
+	}
+}
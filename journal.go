@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// journalctlCommand reads the systemd journal.
+var journalctlCommand = "journalctl"
+
+func init() {
+	registerSource(&Source{Scheme: "journal", Open: openJournal})
+}
+
+// openJournal reads the entries of the unit named by a journal://unit URI,
+// or of the whole journal for journal://, as lines of
+// "<RFC 3339 time> <host> <identifier>: <message>".
+func openJournal(uri string) (io.ReadCloser, error) {
+	args := []string{"--output=json", "--no-pager"}
+	if unit := strings.TrimPrefix(uri, "journal://"); unit != "" {
+		args = append(args, "--unit="+unit)
+	}
+	out, err := startCommand(journalctlCommand, args...)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	j := &journalReader{PipeReader: pr, out: out, done: make(chan struct{})}
+	go func() {
+		pw.CloseWithError(convertJournal(out, pw))
+		close(j.done)
+	}()
+	return j, nil
+}
+
+type journalReader struct {
+	*io.PipeReader
+	out  io.ReadCloser
+	done chan struct{}
+}
+
+func (j *journalReader) Close() error {
+	j.PipeReader.Close()
+	<-j.done
+	return j.out.Close()
+}
+
+// journalEntry holds the fields of journalctl --output=json used here.
+// MESSAGE is a string, or an array of bytes if it isn't valid UTF-8.
+type journalEntry struct {
+	Realtime   string          `json:"__REALTIME_TIMESTAMP"`
+	Hostname   string          `json:"_HOSTNAME"`
+	Identifier string          `json:"SYSLOG_IDENTIFIER"`
+	Message    json.RawMessage `json:"MESSAGE"`
+}
+
+func convertJournal(r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(r)
+	for {
+		var e journalEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("journal: %v", err)
+		}
+		var msg string
+		if json.Unmarshal(e.Message, &msg) != nil {
+			var raw []int
+			json.Unmarshal(e.Message, &raw)
+			b := make([]byte, len(raw))
+			for i, c := range raw {
+				b[i] = byte(c)
+			}
+			msg = string(b)
+		}
+		usec, _ := strconv.ParseInt(e.Realtime, 10, 64)
+		stamp := time.UnixMicro(usec).UTC().Format(time.RFC3339Nano)
+		// a multi-line message is read as several lines, each stamped
+		for _, line := range strings.Split(strings.TrimRight(msg, "\n"), "\n") {
+			if _, err := fmt.Fprintf(w, "%s %s %s: %s\n", stamp, e.Hostname, e.Identifier, line); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConvertJournal(t *testing.T) {
+	in := `{"__REALTIME_TIMESTAMP":"1709287200500000","_HOSTNAME":"web1","SYSLOG_IDENTIFIER":"frontend","MESSAGE":"GET /a 200 12.5"}
+{"__REALTIME_TIMESTAMP":"1709287201000000","_HOSTNAME":"web1","SYSLOG_IDENTIFIER":"frontend","MESSAGE":[80,79,83,84,32,47,98,32,50,48,49,32,52,48]}
+{"__REALTIME_TIMESTAMP":"1709287202000000","_HOSTNAME":"web1","SYSLOG_IDENTIFIER":"frontend","MESSAGE":"GET /c 200 1\nGET /d 200 2\n"}
+`
+	want := `2024-03-01T10:00:00.5Z web1 frontend: GET /a 200 12.5
+2024-03-01T10:00:01Z web1 frontend: POST /b 201 40
+2024-03-01T10:00:02Z web1 frontend: GET /c 200 1
+2024-03-01T10:00:02Z web1 frontend: GET /d 200 2
+`
+	var out bytes.Buffer
+	if err := convertJournal(strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+	if err := convertJournal(strings.NewReader("{not json"), &out); err == nil {
+		t.Error("bad journal output was accepted")
+	}
+}
+
+func TestJournalSource(t *testing.T) {
+	captureLog(t)
+	fakeCommand(t, &journalctlCommand, `[ "$3" = --unit=frontend.service ] || exit 1
+echo '{"__REALTIME_TIMESTAMP":"1709287200000000","_HOSTNAME":"web1","SYSLOG_IDENTIFIER":"frontend","MESSAGE":"GET /a 200 12.5"}'
+`)
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("journal://frontend.service", verbs, TimeWindow{}, c)
+	if values := processLines(c, nil); len(values.Values) != 1 || values.Values[0] != 12.5 {
+		t.Errorf("read %v from the journal, want [12.5]", values.Values)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// kcatCommand is the Kafka command line consumer used to read topics.
+var kcatCommand = "kcat"
+
+func init() {
+	registerSource(&Source{Scheme: "kafka", Open: openKafka, Follows: true})
+}
+
+// kafkaArgs turns kafka://broker[,broker...]/topic[?group=name][&offset=o]
+// into kcat arguments. Without a group the topic is read from offset
+// (default beginning); with one, from the group's committed offsets.
+// kcat exits once it reaches the end of the topic, unless -follow without
+// -until asks for new messages as they come.
+func kafkaArgs(uri string) ([]string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, &ConfigError{"input", err}
+	}
+	topic := strings.Trim(u.Path, "/")
+	if u.Host == "" || topic == "" || strings.Contains(topic, "/") {
+		return nil, &ConfigError{"input", fmt.Errorf("%s: want kafka://broker[,broker...]/topic", uri)}
+	}
+	args := []string{"-C", "-b", u.Host}
+	if !*follow || *untilArg != "" {
+		args = append(args, "-e")
+	}
+	args = append(args, "-q")
+	query := u.Query()
+	if group := query.Get("group"); group != "" {
+		return append(args, "-G", group, topic), nil
+	}
+	offset := query.Get("offset")
+	if offset == "" {
+		offset = "beginning"
+	}
+	return append(args, "-t", topic, "-o", offset), nil
+}
+
+func openKafka(uri string) (io.ReadCloser, error) {
+	args, err := kafkaArgs(uri)
+	if err != nil {
+		return nil, err
+	}
+	return startCommand(kcatCommand, args...)
+}
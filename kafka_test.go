@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKafkaArgs(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want []string
+	}{
+		{"kafka://k1:9092,k2:9092/access", []string{"-C", "-b", "k1:9092,k2:9092", "-e", "-q", "-t", "access", "-o", "beginning"}},
+		{"kafka://k1/access?offset=-1000", []string{"-C", "-b", "k1", "-e", "-q", "-t", "access", "-o", "-1000"}},
+		{"kafka://k1/access?group=metrics", []string{"-C", "-b", "k1", "-e", "-q", "-G", "metrics", "access"}},
+	}
+	for _, tt := range tests {
+		got, err := kafkaArgs(tt.uri)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("kafkaArgs(%s) = %q, %v; want %q", tt.uri, got, err, tt.want)
+		}
+	}
+
+	// -follow streams the topic until interrupted, or until -until
+	defer func(f bool, until string) { *follow, *untilArg = f, until }(*follow, *untilArg)
+	*follow = true
+	if got, _ := kafkaArgs("kafka://k1/access"); !reflect.DeepEqual(got, []string{"-C", "-b", "k1", "-q", "-t", "access", "-o", "beginning"}) {
+		t.Errorf("kafkaArgs with -follow = %q, want no -e", got)
+	}
+	*untilArg = "2024-03-01T11:00:00Z"
+	if got, _ := kafkaArgs("kafka://k1/access"); len(got) < 4 || got[3] != "-e" {
+		t.Errorf("kafkaArgs with -follow and -until = %q, want -e", got)
+	}
+
+	for _, uri := range []string{"kafka:///access", "kafka://k1/", "kafka://k1/a/b"} {
+		if _, err := kafkaArgs(uri); err == nil {
+			t.Errorf("kafkaArgs(%s) succeeded", uri)
+		}
+	}
+}
+
+func TestKafkaSource(t *testing.T) {
+	captureLog(t)
+	fakeCommand(t, &kcatCommand, `cat testdata/access.log`)
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("kafka://k1/access", verbs, TimeWindow{}, c)
+	if values := processLines(c, nil); len(values.Values) != 4 {
+		t.Errorf("read %d GET values from Kafka, want 4", len(values.Values))
+	}
+}
@@ -0,0 +1,38 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// acquireLock takes an exclusive advisory lock on path, writing our pid into
+// it. The lock is released by the kernel when the process exits, so a
+// crashed run never leaves a stale lock behind.
+func acquireLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			buf := make([]byte, 32)
+			n, _ := f.Read(buf)
+			return nil, fmt.Errorf("%s is held by another run (pid %s)", path, strings.TrimSpace(string(buf[:n])))
+		}
+		return nil, err
+	}
+	f.Truncate(0)
+	f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+	return f, nil
+}
+
+func releaseLock(f *os.File) {
+	f.Close()
+}
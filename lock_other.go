@@ -0,0 +1,29 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly)
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// acquireLock creates path exclusively. Unlike the flock version, a run
+// that is killed leaves the file behind and it must be removed by hand.
+func acquireLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%s is held by another run", path)
+		}
+		return nil, err
+	}
+	f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+	return f, nil
+}
+
+func releaseLock(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
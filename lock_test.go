@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.lock")
+	first, err := acquireLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acquireLock(path); err == nil {
+		t.Fatal("second acquireLock succeeded while the first is held")
+	}
+	releaseLock(first)
+
+	again, err := acquireLock(path)
+	if err != nil {
+		t.Fatalf("acquireLock after release: %v", err)
+	}
+	releaseLock(again)
+}
+
+func TestLockReleasedOnFatal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "metrics.lock")
+
+	// a bad option exits before the lock is taken
+	if out, err := tryMetrics(t, dir, "", "-lock="+path, "-clusters=-1", "GET", "access.log"); err == nil {
+		t.Fatalf("bad option accepted:\n%s", out)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file created for a bad option: %v", err)
+	}
+
+	// a fatal error while reading releases it
+	if out, err := tryMetrics(t, dir, "", "-lock="+path, "GET", "missing.log"); err == nil {
+		t.Fatalf("missing input accepted:\n%s", out)
+	}
+	lock, err := acquireLock(path)
+	if err != nil {
+		t.Fatalf("lock left held by a failed run: %v", err)
+	}
+	releaseLock(lock)
+}
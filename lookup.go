@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Lookup is an enrichment table mapping the values of the -lookup-key
+// field to labels such as a customer's plan. Labels can be used wherever a
+// field is, e.g. as -group-key.
+type Lookup struct {
+	Labels map[string]bool
+	Rows   map[string]map[string]string
+}
+
+// lookup is the -lookup table, or nil.
+var lookup *Lookup
+
+// loadLookup reads a lookup table from a JSON object of objects, keyed by
+// the key value, or from a CSV file whose header names the key column
+// first and the labels after it.
+func loadLookup(path string) (*Lookup, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ConfigError{"lookup", err}
+	}
+	l := &Lookup{Labels: make(map[string]bool), Rows: make(map[string]map[string]string)}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = l.readJSON(data)
+	} else {
+		err = l.readCSV(data)
+	}
+	if err != nil {
+		return nil, &ConfigError{"lookup", fmt.Errorf("%s: %v", path, err)}
+	}
+	return l, nil
+}
+
+func (l *Lookup) readJSON(data []byte) error {
+	var rows map[string]map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&rows); err != nil {
+		return err
+	}
+	for key, labels := range rows {
+		row := make(map[string]string, len(labels))
+		for label, v := range labels {
+			if v != nil {
+				row[label] = fmt.Sprint(v)
+			}
+			l.Labels[label] = true
+		}
+		l.Rows[key] = row
+	}
+	return nil
+}
+
+func (l *Lookup) readCSV(data []byte) error {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || len(records[0]) < 2 {
+		return fmt.Errorf("want a header of the key column and at least one label")
+	}
+	header := records[0]
+	for _, label := range header[1:] {
+		l.Labels[strings.TrimSpace(label)] = true
+	}
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header)-1)
+		for i, v := range record[1:] {
+			row[strings.TrimSpace(header[i+1])] = v
+		}
+		l.Rows[record[0]] = row
+	}
+	return nil
+}
+
+// Label returns the label of the row for the -lookup-key field of line,
+// if there is one.
+func (l *Lookup) Label(line string, obj jsonObject, label string) (string, bool) {
+	key, ok := lineField(line, obj, *lookupKey)
+	if !ok {
+		return "", false
+	}
+	v, ok := l.Rows[key][label]
+	return v, ok
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	captureLog(t)
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "hosts.csv")
+	os.WriteFile(csvPath, []byte("host,plan,shard\nhost1,pro,1\nhost2,free,2\n"), 0644)
+	jsonPath := filepath.Join(dir, "hosts.json")
+	os.WriteFile(jsonPath, []byte(`{"host1": {"plan": "pro", "shard": 1}, "host2": {"plan": "free", "shard": 2}}`), 0644)
+
+	defer func(key string) { *lookupKey = key; lookup = nil }(*lookupKey)
+	*lookupKey = "2"
+	verbs, _ := parseVerbs("GET,POST,DELETE")
+	for _, path := range []string{csvPath, jsonPath} {
+		var err error
+		if lookup, err = loadLookup(path); err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := lineField("2024-03-01T10:00:00Z host2 GET /api 200 8", nil, "shard"); v != "2" || !ok {
+			t.Errorf("%s: shard = %q, %v; want 2", path, v, ok)
+		}
+		if _, ok := lineField("2024-03-01T10:00:00Z host3 GET /api 200 8", nil, "plan"); ok {
+			t.Errorf("%s: found a plan for a host not in the table", path)
+		}
+
+		c := make(chan LineMatch, ChanSize)
+		go filterValues("testdata/access.log", verbs, TimeWindow{}, c)
+		values := processLines(c, groupByKey("plan"))
+		counts := map[string]int{}
+		for name, g := range values.Groups {
+			counts[name] = len(g.Values)
+		}
+		if want := map[string]int{"pro": 3, "free": 4}; !reflect.DeepEqual(counts, want) {
+			t.Errorf("%s: values per plan = %v, want %v", path, counts, want)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.csv")
+	os.WriteFile(bad, []byte("host\nhost1\n"), 0644)
+	if _, err := loadLookup(bad); err == nil {
+		t.Error("lookup table without labels was accepted")
+	}
+}
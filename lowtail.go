@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// lowTailPercentiles are the percentiles -low-tail adds below the regular
+// ones.
+var lowTailPercentiles = []float64{0.1, 1}
+
+// lowTail are the low-tail percentiles reported; main sets them from
+// -low-tail.
+var lowTail []float64
+
+// lowPercentile returns the value at fractional percentile p of sorted.
+func lowPercentile(sorted []float32, p float64) float32 {
+	pos := int(p * float64(len(sorted)) / 100)
+	return sorted[min(pos, len(sorted)-1)]
+}
+
+func formatLowTail(p float64) string {
+	return "P" + strconv.FormatFloat(p, 'g', -1, 64) + "%"
+}
+
+// fastThresholds map verbs to the value below which -too-fast flags their
+// lines as suspiciously fast; the "" entry applies to all other verbs.
+var fastThresholds map[string]float32
+
+// parseFastThresholds parses -too-fast: comma-separated thresholds, each
+// either "verb=value" or a bare value for the verbs not listed.
+func parseFastThresholds(s string) (map[string]float32, error) {
+	if s == "" {
+		return nil, nil
+	}
+	thresholds := make(map[string]float32)
+	for _, part := range strings.Split(s, ",") {
+		verb, value := "", strings.TrimSpace(part)
+		if i := strings.LastIndexByte(part, '='); i >= 0 {
+			verb, value = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+			if verb == "" {
+				return nil, &ConfigError{"too-fast", fmt.Errorf("no verb before %q", part)}
+			}
+		}
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return nil, &ConfigError{"too-fast", fmt.Errorf("bad threshold %q", part)}
+		}
+		if _, dup := thresholds[verb]; dup {
+			return nil, &ConfigError{"too-fast", fmt.Errorf("more than one threshold for %q", verb)}
+		}
+		thresholds[verb] = float32(v)
+	}
+	return thresholds, nil
+}
+
+// fastThreshold returns the -too-fast threshold of verb, if it has one.
+func fastThreshold(verb string) (float32, bool) {
+	t, ok := fastThresholds[verb]
+	if !ok {
+		t, ok = fastThresholds[""]
+	}
+	return t, ok
+}
+
+func isTooFast(verb string, val float32) bool {
+	t, ok := fastThreshold(verb)
+	return ok && val < t
+}
+
+// printTooFast logs how many values of each verb were below its -too-fast
+// threshold, estimated for all lines with -sample. names are the reported
+// names of verbs.
+func printTooFast(verbs, names []string, values AggregatedValues) {
+	summary := ""
+	for i, verb := range verbs {
+		if n := values.TooFast[verb]; n > 0 {
+			t, _ := fastThreshold(verb)
+			summary += fmt.Sprintf("  %s: %d of %d values (%.2f%%) below %s\n", names[i], scaleCount(n), scaleCount(values.Counts[verb]),
+				float32(n)*100/float32(values.Counts[verb]), formatThreshold(t))
+		}
+	}
+	if summary == "" {
+		log.Print("no suspiciously fast values")
+		return
+	}
+	log.Print("suspiciously fast values, likely cached or failed responses:\n" + summary)
+}
+
+func writeMarkdownTooFast(w io.Writer, verbs, names []string, values AggregatedValues) {
+	var rows []string
+	for i, verb := range verbs {
+		if n := values.TooFast[verb]; n > 0 {
+			t, _ := fastThreshold(verb)
+			rows = append(rows, fmt.Sprintf("| %s | %s | %d | %.2f%% |", strings.ReplaceAll(names[i], "|", `\|`),
+				formatThreshold(t), scaleCount(n), float32(n)*100/float32(values.Counts[verb])))
+		}
+	}
+	if len(rows) == 0 {
+		fmt.Fprintf(w, "\nNo suspiciously fast values.\n")
+		return
+	}
+	fmt.Fprintf(w, "\nSuspiciously fast values, likely cached or failed responses:\n\n| verb | below | count | share |\n|---|---:|---:|---:|\n")
+	fmt.Fprintln(w, strings.Join(rows, "\n"))
+}
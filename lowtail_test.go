@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFastThresholds(t *testing.T) {
+	got, err := parseFastThresholds("0.5, GET /api=2,status=200=1")
+	want := map[string]float32{"": 0.5, "GET /api": 2, "status=200": 1}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFastThresholds = %v, %v; want %v", got, err, want)
+	}
+	for _, s := range []string{"x", "=1", "GET=1,GET=2", "1,2"} {
+		if _, err := parseFastThresholds(s); err == nil {
+			t.Errorf("parseFastThresholds(%q) succeeded", s)
+		}
+	}
+}
+
+func TestLowTailAndTooFast(t *testing.T) {
+	buf := captureLog(t)
+	lowTail = lowTailPercentiles
+	fastThresholds = map[string]float32{"": 1, "POST": 50}
+	defer func() { lowTail, fastThresholds = nil, nil }()
+
+	verbs, _ := parseVerbs("GET,POST")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("testdata/access.log", verbs, TimeWindow{}, c)
+	values := processLines(c, nil)
+	if want := map[string]int{"GET": 1, "POST": 1}; !reflect.DeepEqual(values.TooFast, want) {
+		t.Errorf("TooFast = %v, want %v", values.TooFast, want)
+	}
+	summary := computePercentiles(values, []int{50})
+	if want := []float32{0.5, 0.5}; !reflect.DeepEqual(summary.LowTail, want) {
+		t.Errorf("LowTail = %v, want %v", summary.LowTail, want)
+	}
+	if empty := computePercentiles(AggregatedValues{}, []int{50}); !reflect.DeepEqual(empty.LowTail, []float32{-1, -1}) {
+		t.Errorf("empty LowTail = %v", empty.LowTail)
+	}
+
+	buf.Reset()
+	printTooFast(verbs.Verbs, verbs.Verbs, values)
+	if !strings.Contains(buf.String(), "POST: 1 of 2 values (50.00%) below 50") {
+		t.Errorf("printTooFast logged:\n%s", buf)
+	}
+}
@@ -2,24 +2,51 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"runtime/debug"
 	"runtime/pprof"
 	"sort"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 )
 
 type Verbs struct {
 	Verbs []string
+	// Matchers[i] decides whether a line belongs to Verbs[i].
+	Matchers []Matcher
 }
 
 type AggregatedValues struct {
 	Values Float32Slice
 	Counts map[string]int
 	Accum  float32
+	// Groups holds the values of each breakdown row separately, when a
+	// breakdown was requested.
+	Groups map[string]*AggregatedValues
+	// Gaps lists the stretches without matched lines longer than -gap.
+	Gaps []Gap
+	// Classes holds the values of each status class, with -status-key.
+	Classes map[string]*AggregatedValues
+	// Tenants holds the values of each tenant, with -tenant-key.
+	Tenants map[string]*AggregatedValues
+	// Joined holds the values of the joinComponents of the lines joined
+	// with -join, and Unjoined counts the other lines.
+	Joined   map[string]*AggregatedValues
+	Unjoined int
+	// TooFast counts the values of each verb below its -too-fast threshold.
+	TooFast map[string]int
+	// Verbs holds the values of each verb, with -budget.
+	Verbs map[string]*AggregatedValues
+	// Variants holds the values of each variant compared, with -canary or
+	// -variant-key.
+	Variants map[string]*AggregatedValues
 }
 
 type PercentileValues struct {
@@ -28,11 +55,15 @@ type PercentileValues struct {
 	Average     float32
 	Min         float32
 	Max         float32
+	Under       []float32 // share of values at or below each -under threshold
+	LowTail     []float32 // values at each -low-tail percentile
 }
 
 type LineMatch struct {
-	Line string
-	Verb string
+	Line   string
+	Verb   string
+	File   string
+	Object jsonObject // the decoded -format=json line, or nil
 }
 
 const ChanSize = 10000
@@ -40,93 +71,996 @@ const BuffSize = 1000 * 1000
 
 var PERCENTILES = [...]int{10, 50, 90, 99, 100}
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
+var configFile = flag.String("config", "", "read flag defaults and verbs from `file` of \"name = value\" lines")
+var profile = flag.String("profile", "", "load the config file `name`.conf from the profiles directory (~/.config/metrics/profiles)")
+var ignoreCase = flag.Bool("ignore-case", false, "match verbs ignoring case and common Latin diacritics")
+var sinceArg = flag.String("since", "", "skip lines stamped before this time (2006-01-02T15:04:05Z, or a duration ago like 2h)")
+var untilArg = flag.String("until", "", "skip lines stamped at or after this time, same format as -since")
+var assumeSorted = flag.Bool("assume-sorted", false, "the input is in timestamp order: binary search for -since and stop after -until")
+var useIndex = flag.Bool("index", false, "keep a <file>.idx sidecar of timestamps by offset, and use it to seek to -since")
+var strict = flag.Bool("strict", false, "exit on the first unparsable value, unreadable line, failed seek or index write instead of logging and going on")
+var readRetries = flag.Int("read-retries", 3, "reopen the file and retry this many times after a transient read error (EIO, ESTALE)")
+var retryDelay = flag.Duration("retry-delay", time.Second, "wait before the first read retry, growing linearly with each attempt")
+var inputUnit = flag.String("input-unit", "", "unit of the logged values, ns, us, ms or s; values are converted to milliseconds")
+var interArrival = flag.Bool("inter-arrival", false, "report percentiles of the milliseconds between consecutive lines of each verb instead of a logged value")
+var gapThreshold = flag.Duration("gap", 0, "report stretches longer than this `duration` without any matched line, e.g. 60s")
+var manifestFile = flag.String("manifest", "", "write the size, mtime and SHA-256 of the analyzed input to `file` as JSON")
+var include = flag.String("include", "*", "glob `pattern` for the names of files read from directory and s3://bucket/prefix/ arguments, e.g. '*.log*'")
+var anonymizeFile = flag.String("anonymize", "", "replace verb, path and other row names in the report, and lines quoted in warnings, with stable pseudonyms, kept with their names in the private mapping `file`")
+var syslogAddr = flag.String("listen-syslog", "", "read syslog messages sent to UDP and TCP `addr`, e.g. :5514, until interrupted, instead of files")
+var journal = flag.Bool("journal", false, "read the systemd journal with journalctl instead of files")
+var journalUnit = flag.String("unit", "", "with -journal, only read the entries of this systemd `unit`")
+var lineFormat = flag.String("format", "text", "line format: text (whitespace-separated fields), json (one object per line), logfmt (key=value pairs), csv, nginx (combined log format with $request_time) or alb (AWS ALB and classic ELB access logs)")
+var verbKey = flag.String("verb-key", "", "match verbs against this field instead of the whole line: a key, or a field number for -format=text")
+var timeKey = flag.String("time-key", "", "read timestamps from this field: a key, or a field number for -format=text")
+var valueKey = flag.String("value-key", "", "read the value from this field: a key (dotted for nested JSON), or a field number for -format=text")
+var underArg = flag.String("under", "", "also report the share of values at or below each of these comma-separated `thresholds`, e.g. 250,500")
+var csvHeader = flag.Bool("csv-header", true, "with -format=csv, the first line of each input names the columns")
+var groupKey = flag.String("group-key", "", "field whose values are the rows of -group-by=key: a key, CSV column or field number")
+var cdfFile = flag.String("cdf", "", "write the cumulative distribution of each verb, or each breakdown row, to `file`: JSON if it ends in .json, CSV otherwise")
+var cdfPoints = flag.Int("cdf-points", 0, "downsample -cdf to this many evenly spaced fractions; 0 keeps a point for each distinct value")
+var showLowTail = flag.Bool("low-tail", false, "also report the P0.1% and P1% percentiles of the fastest values")
+var tooFast = flag.String("too-fast", "", "report values below these comma-separated thresholds as suspiciously fast, e.g. 1 or 0.5,GET /api=2 for a verb")
+var statusKey = flag.String("status-key", "", "field holding the HTTP status: a key, or a field number for -format=text; set by -format=nginx and alb")
+var splitStatus = flag.Bool("split-status", true, "when the status is known from -status-key, also report percentiles per status class (2xx, 4xx, 5xx)")
+var tenantKey = flag.String("tenant-key", "", "field holding the tenant or customer ID: also report the top tenants by requests, total value and P99")
+var tenantTop = flag.Int("tenant-top", 10, "number of tenants in each -tenant-key list; 0 lists all")
+var follow = flag.Bool("follow", false, "keep reading the file as it grows, reopening it when rotated like tail -F, or a kafka:// topic as messages arrive, until interrupted")
+var lookupFile = flag.String("lookup", "", "enrich lines with the labels of a CSV or JSON (by extension) `file` keyed by the -lookup-key field, usable as keys")
+var lookupKey = flag.String("lookup-key", "", "field whose value is looked up in -lookup: a key, or a field number for -format=text")
+var multiline = flag.String("multiline", "", "join lines into records before matching: \"timestamp\" starts a record at each stamped line, anything else is a `regexp` matching record starts")
+var joinFile = flag.String("join", "", "join the lines with those of a second log `file` by request ID, and report the total, joined and rest (total minus joined) values. The whole file is read into memory first, about 100 bytes per request ID plus the ID; see -join-max-ids")
+var joinMaxIDs = flag.Int("join-max-ids", 10000000, "fail rather than hold more than `n` request IDs of the -join log in memory; 0 for no limit")
+var joinKey = flag.String("join-key", "", "field of the request ID in the input lines for -join")
+var joinFormat = flag.String("join-format", "", "line format of the -join log; defaults to -format")
+var joinIDKey = flag.String("join-id-key", "", "field of the request ID in the -join log; defaults to -join-key")
+var joinValueKey = flag.String("join-value-key", "", "field of the value in the -join log; defaults to the last field")
+var joinUnit = flag.String("join-unit", "", "unit of the -join log values, ns, us, ms or s; defaults to that of a -join-format preset, or -input-unit if it is -format")
+var delimiter = flag.String("delimiter", "", "records end with this string instead of a newline, e.g. \\0 for NUL or \\x1e; Go escapes are accepted")
+var sampleArg = flag.Float64("sample", 1, "only read this random share of lines, e.g. 0.1, scaling counts up to estimates")
+var sampleSeed = flag.Int64("seed", 1, "seed of the -sample random choice; the same seed samples the same lines")
+var stateFile = flag.String("state", "", "record in `file` how far each plain local file was read, for -resume")
+var resume = flag.Bool("resume", false, "continue each file from where the last run with the same -state stopped, e.g. for cron runs over a growing log")
+var parallel = flag.Int("parallel", 1, "scan each plain local file of at least 32MB in up to `n` line-aligned shards at once, e.g. the number of cores; counts are the same, only faster")
+var useMmap = flag.Bool("mmap", false, "read plain local files through a memory mapping, copying only the matched lines; less garbage on huge files with few matches")
+var budgetArg = flag.String("budget", "", "report each verb's P50 and P95 as a share of this total latency budget, e.g. 1000 for a 1s page load, and the verbs over it or over their own allowance, e.g. 1000,GET /api/cart=300")
+var clusters = flag.Int("clusters", 0, "group the verbs, or breakdown rows, into up to `k` clusters of similar latency distributions, e.g. endpoints sharing a slow dependency")
+var canary = flag.String("canary", "", "lines matching this `verb` (a substring or expression) are the canary's: compare their percentiles with the other lines', testing the difference for significance")
+var variantKey = flag.String("variant-key", "", "field holding the experiment variant, e.g. an A/B bucket: report each variant's percentiles and compare them with -variant-baseline's, testing the difference for significance")
+var variantBaseline = flag.String("variant-baseline", "control", "the -variant-key variant the others are compared with")
+var valueField = flag.Int("value-field", 0, "1-based whitespace field holding the value; negative counts from the end, 0 is the last field")
+var lockFile = flag.String("lock", "", "exit instead of running if another run holds the lock `file`")
+var showTimings = flag.Bool("timings", false, "report time spent in each processing stage")
+var breakdown = flag.Bool("breakdown", false, "also print percentiles for each verb, or each -group-by row")
+var groupBy = flag.String("group-by", "verb", "breakdown rows: verb, file, network, user-agent, size or key (implies -breakdown unless verb)")
+var sizeField = flag.Int("size-field", 0, "1-based whitespace field holding the request size for -group-by=size; negative counts from the end")
+var sizeBuckets = flag.String("size-buckets", "1k,10k,100k,1M", "request size bucket boundaries for -group-by=size")
+var networksFile = flag.String("networks", "", "`file` of \"<cidr> <name>\" lines used by -group-by=network")
+var pathTree = flag.Bool("path-tree", false, "print percentiles per URL path prefix as a tree")
+var pathDepth = flag.Int("path-depth", 3, "number of path segments shown by -path-tree")
+var sortBy = flag.String("sort-by", "name", "order breakdown rows by name, count, min, avg, max or a percentile (p99)")
+var sortDesc = flag.Bool("desc", false, "sort breakdown rows in descending order")
+var outputFormat = flag.String("output", "text", "report format: text or markdown")
+var templateFile = flag.String("template", "", "render the report with the Go text/template in `file` instead of -output")
+var colorThresholds = flag.String("color-thresholds", "", "color percentile cells green/yellow/red against `warn,crit` values")
+var noColor = flag.Bool("no-color", false, "disable colored output")
+var top = flag.Int("top", 0, "only print the first N breakdown rows plus one row aggregating the rest (implies -breakdown)")
 
 func main() {
-	argOffset := 1
+	flag.Usage = usage
 	flag.Parse()
+	var configVerbs string
+	var configPaths []string
+	if *configFile != "" {
+		configPaths = append(configPaths, *configFile)
+	}
+	if *profile != "" {
+		path, err := profilePath(*profile)
+		if err != nil {
+			fatal(err)
+		}
+		configPaths = append(configPaths, path)
+	}
+	// earlier files win, as applyConfig leaves flags that are already set
+	for _, path := range configPaths {
+		config, err := loadConfig(path)
+		if err != nil {
+			fatal(err)
+		}
+		verbs, err := applyConfig(flag.CommandLine, config)
+		if err != nil {
+			fatal(err)
+		}
+		if configVerbs == "" {
+			configVerbs = verbs
+		}
+	}
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
 		if err != nil {
-			log.Fatal(err)
+			fatal(err)
 		}
 		pprof.StartCPUProfile(f)
 		defer pprof.StopCPUProfile()
-		argOffset++
 	}
+	stageTimes.Enabled = *showTimings
 
-	arg := os.Args[argOffset:]
+	arg := flag.Args()
+	if len(arg) > 0 {
+		if cmd, ok := commands[arg[0]]; ok {
+			cmd.Run(arg[1:])
+			return
+		}
+	}
+	// with verbs from a config, all arguments may be files
+	if configVerbs != "" && len(arg) >= 1 {
+		if len(arg) == 1 || isInputArg(arg[0]) {
+			arg = append([]string{configVerbs}, arg...)
+		}
+	}
+	// the inputs selected by flags instead of arguments
+	var flagInput string
+	switch {
+	case *syslogAddr != "":
+		flagInput = "syslog://" + *syslogAddr
+	case *journal:
+		flagInput = "journal://" + *journalUnit
+	}
+	if flagInput != "" {
+		if len(arg) == 0 && configVerbs != "" {
+			arg = []string{configVerbs}
+		}
+		if len(arg) == 1 {
+			arg = append(arg, flagInput)
+		}
+	}
+	if len(arg) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
 
-	f := func(c rune) bool {
-		return c == ','
+	verbs, err := parseVerbs(arg[0])
+	if err != nil {
+		fatal(err)
+	}
+	grouping, err := newGrouping(*groupBy)
+	if err != nil {
+		fatal(err)
+	}
+	sortKey, err := parseSortKey(*sortBy, PERCENTILES[:])
+	if err != nil {
+		fatal(err)
+	}
+	if *lookupFile != "" {
+		if *lookupKey == "" {
+			fatal(&ConfigError{"lookup-key", errors.New("-lookup needs the field to look up")})
+		}
+		if lookup, err = loadLookup(*lookupFile); err != nil {
+			fatal(err)
+		}
+		if lookup.Labels[*lookupKey] {
+			fatal(&ConfigError{"lookup-key", fmt.Errorf("%q is a label of the lookup table", *lookupKey)})
+		}
+	}
+	if err := checkLineFormat(); err != nil {
+		fatal(err)
+	}
+	if err := checkOutputFormat(*outputFormat); err != nil {
+		fatal(err)
+	}
+	var tmpl *template.Template
+	if *templateFile != "" {
+		tmpl, err = loadReportTemplate(*templateFile)
+		if err != nil {
+			fatal(err)
+		}
+	}
+	if *colorThresholds != "" {
+		thresholds, err := parseThresholds(*colorThresholds)
+		if err != nil {
+			fatal(err)
+		}
+		if !*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stderr) {
+			cellColors = thresholds
+		}
+	}
+
+	if underThresholds, err = parseThresholdList("under", *underArg); err != nil {
+		fatal(err)
+	}
+	if fastThresholds, err = parseFastThresholds(*tooFast); err != nil {
+		fatal(err)
+	}
+	if *clusters < 0 {
+		fatal(&ConfigError{"clusters", fmt.Errorf("%d clusters, want 0 for none or more", *clusters)})
+	}
+	if *canary != "" {
+		if canaryMatcher, err = parseMatcher(*canary); err != nil {
+			fatal(&ConfigError{"canary", err})
+		}
+		if *variantKey != "" {
+			fatal(&ConfigError{"canary", errors.New("compares its own variants, so not with -variant-key")})
+		}
+	}
+	if budget, err = parseBudget(*budgetArg); err != nil {
+		fatal(err)
+	}
+	if budget != nil {
+		for verb := range budget.Allowances {
+			if !containsString(verbs.Verbs, verb) {
+				fatal(&ConfigError{"budget", fmt.Errorf("%q is not one of the verbs", verb)})
+			}
+		}
+	}
+	if *showLowTail {
+		lowTail = lowTailPercentiles
+	}
+	if *multiline != "" {
+		// checked here, each input gets its own joiner
+		if _, err := newRecordJoiner(*multiline); err != nil {
+			fatal(err)
+		}
+	}
+	if *resume && *stateFile == "" {
+		fatal(&ConfigError{"resume", errors.New("-resume needs a -state file")})
+	}
+	if err := setSampling(*sampleArg, *sampleSeed); err != nil {
+		fatal(err)
+	}
+	if recordDelimiter, err = parseDelimiter(*delimiter); err != nil {
+		fatal(err)
+	}
+	if valueScale, err = parseInputUnit(*inputUnit); err != nil {
+		fatal(err)
+	}
+	if *joinFile != "" {
+		if joinLog, err = loadJoinLog(); err != nil {
+			fatal(err)
+		}
 	}
 
-	verbs := Verbs{
-		Verbs: strings.FieldsFunc(arg[0], f),
+	window := TimeWindow{Sorted: *assumeSorted}
+	now := time.Now()
+	if window.Since, err = parseTimeArg("since", *sinceArg, now); err != nil {
+		fatal(err)
+	}
+	if window.Until, err = parseTimeArg("until", *untilArg, now); err != nil {
+		fatal(err)
+	}
+	queryWindow = window
+
+	// the options are valid: from here on, this is the run holding -lock,
+	// and it reads the -state after the run before it saved it
+	if *lockFile != "" {
+		lock, err := acquireLock(*lockFile)
+		if err != nil {
+			fatal(err)
+		}
+		atExit = append(atExit, func() { releaseLock(lock) })
+		defer releaseLock(lock)
+	}
+	if *stateFile != "" {
+		if checkpoints, err = loadCheckpoints(*stateFile, *resume); err != nil {
+			fatal(err)
+		}
 	}
 
-	log.Printf("%s, looking for verbs:%v", arg[1], verbs.Verbs)
+	if *parallel < 1 {
+		fatal(&ConfigError{"parallel", fmt.Errorf("%d shards, want at least 1", *parallel)})
+	}
+	shardCount = *parallel
+	if conflict := parallelConflict(window); shardCount > 1 && conflict != "" {
+		log.Printf("%s needs lines in order, reading files without -parallel", conflict)
+		shardCount = 1
+	}
+
+	inputArgs, region := trailingRegion(arg[1:])
+	inputs, err := expandInputs(inputArgs, *include)
+	if err != nil {
+		fatal(err)
+	}
+	inputNames := strings.Join(inputs, ", ")
+	if *follow && (len(inputs) != 1 || inputs[0] == "-" || !followable(inputs[0])) {
+		fatal(&ConfigError{"follow", fmt.Errorf("can only follow a single local file or Kafka topic, not %s", inputNames)})
+	}
+
+	var manifest Manifest
+	if *manifestFile != "" {
+		manifest = Manifest{Created: now.UTC(), Verbs: verbs.Verbs}
+		for _, input := range inputs {
+			if isStream(input) {
+				fatal(&ConfigError{"manifest", fmt.Errorf("can't hash %s, only local files", input)})
+			}
+			entry, err := statManifestEntry(input)
+			if err != nil {
+				fatal(err)
+			}
+			manifest.Files = append(manifest.Files, entry)
+		}
+	}
+
+	reportVerbs := verbs.Verbs
+	if *anonymizeFile != "" {
+		if anon, err = loadAnonymizer(*anonymizeFile); err != nil {
+			fatal(err)
+		}
+		reportVerbs = make([]string, len(verbs.Verbs))
+		for i, v := range verbs.Verbs {
+			reportVerbs[i] = anon.Name(v)
+		}
+	}
+
+	if region != "" {
+		log.Printf("%s in %s, looking for verbs:%v", inputNames, region, reportVerbs)
+	} else {
+		log.Printf("%s, looking for verbs:%v", inputNames, reportVerbs)
+	}
+	if sampler != nil {
+		log.Printf("reading a %g%% sample of lines, counts are estimates", sampleRate*100)
+	}
 	c := make(chan LineMatch, ChanSize)
-	go filterValues(arg[1], verbs, c)
-	values := processLines(c)
+	go filterFiles(inputs, verbs, window, c)
+	switch {
+	case *pathTree:
+		grouping = groupByPathPrefix(*pathDepth)
+	case !*breakdown && *top == 0 && *groupBy == "verb":
+		grouping = nil
+	}
+	collect := grouping
+	if collect == nil && (*cdfFile != "" || *clusters > 0) {
+		// -cdf and -clusters are per verb also without a breakdown
+		collect = groupByVerb
+	}
+	values := processLines(c, collect)
+	if len(values.Values) == 0 {
+		warnf("%s: no values found for verbs %v", inputNames, reportVerbs)
+	}
+	if *inputUnit == "" && !*interArrival {
+		warnInputUnit(values.Values)
+	}
+	// pseudonyms replace the row names before the rows are sorted and cut
+	// to -top, so that neither their order nor the "(remaining N)" row
+	// gives the names away; rows are then ordered by pseudonym
+	nameLess := func(a, b string) bool { return a < b }
+	if collect != nil && collect.NameLess != nil {
+		nameLess = collect.NameLess
+	}
+	baseline := baselineVariant()
+	if anon != nil {
+		rename := anon.Row
+		if *pathTree {
+			rename = anon.Path
+		}
+		values.Groups = renameGroups(values.Groups, rename)
+		values.Classes = renameGroups(values.Classes, anon.Row)
+		values.Tenants = renameGroups(values.Tenants, anon.Row)
+		if *variantKey != "" {
+			values.Variants = renameGroups(values.Variants, anon.Row)
+			baseline = anon.Row(baseline)
+		}
+		nameLess = nil
+	}
+	start := stageTimes.Now()
 	percentiles := computePercentiles(values, PERCENTILES[:])
+	var groups []GroupPercentiles
+	switch {
+	case *pathTree:
+		groups = computeGroupPercentiles(values, PERCENTILES[:])
+		sortPathTree(groups)
+	case grouping != nil:
+		groups = computeGroupPercentiles(values, PERCENTILES[:])
+		sortGroups(groups, sortKey, *sortDesc, nameLess)
+		groups = limitGroups(groups, values, *top, PERCENTILES[:])
+	}
+	var classes []GroupPercentiles
+	if values.Classes != nil {
+		classes = computeGroupPercentiles(AggregatedValues{Groups: values.Classes}, PERCENTILES[:])
+		sortGroups(classes, SortKey{Field: "name"}, false, nil)
+	}
+	var tenants []TenantRanking
+	if values.Tenants != nil {
+		rows := computeGroupPercentiles(AggregatedValues{Groups: values.Tenants}, PERCENTILES[:])
+		tenants = rankTenants(rows, *tenantTop)
+	}
+	var variants []GroupPercentiles
+	var comparisons []VariantComparison
+	if values.Variants != nil {
+		variants = computeGroupPercentiles(AggregatedValues{Groups: values.Variants}, PERCENTILES[:])
+		sortGroups(variants, SortKey{Field: "name"}, false, nil)
+		comparisons = compareVariants(variants, values.Variants, baseline, PERCENTILES[:])
+		if *variantKey != "" && len(variants) > 0 && values.Variants[baseline] == nil {
+			warnf("no lines of the -variant-baseline %q to compare with", *variantBaseline)
+		}
+	}
+	var budgetShares []BudgetRow
+	if budget != nil {
+		budgetShares = budgetRows(verbs.Verbs, reportVerbs, values, budget)
+	}
+	stageTimes.Since(StagePercentiles, start)
+	if *cdfFile != "" {
+		cdfs := computeCDFs(values.Groups, *cdfPoints, nameLess)
+		if err := writeCDFs(*cdfFile, collect.Label, cdfs); err != nil {
+			fatal(err)
+		}
+	}
+	var clustered []Cluster
+	if *clusters > 0 {
+		clustered = computeClusters(values.Groups, *clusters)
+	}
 
-	printPercentiles(percentiles)
+	switch {
+	case tmpl != nil:
+		report := Report{
+			Verbs:       reportVerbs,
+			File:        inputNames,
+			Files:       inputs,
+			Percentiles: PERCENTILES[:],
+			Under:       underThresholds,
+			LowTail:     lowTail,
+			Summary:     percentiles,
+			Groups:      groups,
+			Gaps:        values.Gaps,
+			Classes:     classes,
+			Tenants:     tenants,
+			Budget:      budgetShares,
+			Clusters:    clustered,
+			Variants:    variants,
+			Comparisons: comparisons,
+		}
+		if grouping != nil {
+			report.GroupLabel = grouping.Label
+		}
+		if err := tmpl.Execute(os.Stdout, report); err != nil {
+			fatal(err)
+		}
+	case *outputFormat == "markdown":
+		label := ""
+		if grouping != nil {
+			label = grouping.Label
+		}
+		writeMarkdown(os.Stdout, percentiles, label, groups, PERCENTILES[:])
+		if clustered != nil {
+			writeMarkdownClusters(os.Stdout, collect.Label, clustered)
+		}
+		if classes != nil {
+			fmt.Fprintln(os.Stdout)
+			writeMarkdownGroups(os.Stdout, groupByStatusClass.Label, classes, PERCENTILES[:])
+		}
+		if tenants != nil {
+			writeMarkdownTenants(os.Stdout, tenants, percentiles)
+		}
+		if budget != nil {
+			writeMarkdownBudget(os.Stdout, budgetShares, budget)
+		}
+		if variants != nil {
+			writeMarkdownVariants(os.Stdout, "variant", variants, comparisons, PERCENTILES[:])
+		}
+		if joinLog != nil {
+			writeMarkdownJoin(os.Stdout, values, joinLog, PERCENTILES[:])
+		}
+		if *gapThreshold > 0 {
+			writeMarkdownGaps(os.Stdout, values.Gaps, *gapThreshold)
+		}
+		if fastThresholds != nil {
+			writeMarkdownTooFast(os.Stdout, verbs.Verbs, reportVerbs, values)
+		}
+	default:
+		printPercentiles(percentiles)
+		switch {
+		case *pathTree:
+			printPathTree(groups)
+		case grouping != nil:
+			printGroups(grouping.Label, groups, PERCENTILES[:])
+		}
+		if clustered != nil {
+			printClusters(collect.Label, clustered)
+		}
+		if classes != nil {
+			printGroups(groupByStatusClass.Label, classes, PERCENTILES[:])
+		}
+		if tenants != nil {
+			printTenants(tenants, percentiles)
+		}
+		if budget != nil {
+			printBudget(budgetShares, budget)
+		}
+		if variants != nil {
+			printVariants("variant", variants, comparisons, PERCENTILES[:])
+		}
+		if joinLog != nil {
+			printJoin(values, joinLog, PERCENTILES[:])
+		}
+		if *gapThreshold > 0 {
+			printGaps(values.Gaps, *gapThreshold)
+		}
+		if fastThresholds != nil {
+			printTooFast(verbs.Verbs, reportVerbs, values)
+		}
+	}
+	if anon != nil {
+		if err := anon.Save(); err != nil {
+			fatal(err)
+		}
+	}
+	if checkpoints != nil {
+		if err := checkpoints.Save(); err != nil {
+			fatal(err)
+		}
+	}
+	if *manifestFile != "" {
+		for i := range manifest.Files {
+			if err := manifest.Files[i].hash(); err != nil {
+				fatal(err)
+			}
+		}
+		if err := writeManifest(*manifestFile, manifest); err != nil {
+			fatal(err)
+		}
+	}
+	if stageTimes.Enabled {
+		printStageTimes(&stageTimes)
+	}
+}
+
+// parseVerbs parses a comma-separated list of verbs. Each verb is either a
+// plain substring or an expression (see parseExpr), which is named after its
+// trimmed text.
+func parseVerbs(arg string) (Verbs, error) {
+	var verbs Verbs
+	for _, def := range splitVerbDefs(arg) {
+		m, err := parseMatcher(def)
+		if err != nil {
+			return verbs, &ConfigError{"verbs", err}
+		}
+		if isExpression(def) {
+			def = strings.TrimSpace(def)
+		}
+		verbs.Verbs = append(verbs.Verbs, def)
+		verbs.Matchers = append(verbs.Matchers, m)
+	}
+	if len(verbs.Verbs) == 0 {
+		return verbs, &ConfigError{"verbs", errors.New("no verbs given")}
+	}
+	return verbs, nil
+}
+
+// parseMatcher parses one verb: a plain substring or an expression.
+func parseMatcher(def string) (Matcher, error) {
+	if !isExpression(def) {
+		return newSubstringMatcher(def), nil
+	}
+	m, err := parseExpr(def)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", def, err)
+	}
+	return m, nil
+}
+
+// filterFiles sends the matching lines of each file in turn to channel,
+// then closes it.
+func filterFiles(filenames []string, verbs Verbs, window TimeWindow, channel chan LineMatch) {
+	for _, filename := range filenames {
+		scanFile(filename, verbs, window, channel)
+	}
+	close(channel)
+}
+
+func filterValues(filename string, verbs Verbs, window TimeWindow, channel chan LineMatch) {
+	filterFiles([]string{filename}, verbs, window, channel)
 }
 
-func filterValues(filename string, verbs Verbs, channel chan LineMatch) {
+func scanFile(filename string, verbs Verbs, window TimeWindow, channel chan LineMatch) {
+
+	rc, err := openInput(filename)
+	if err != nil {
+		fatal(err)
+	}
+	// streams and offsets into compressed input can't be seeked
+	f, _ := rc.(*os.File)
+	stream := isStream(filename)
+	format := ""
+	if !stream {
+		format = compression(f)
+	}
+	// seeking finds line starts, so it needs newline-separated records
+	seekable := !stream && format == "" && recordDelimiter == nil
+	if !seekable && (*useIndex || (window.Sorted && !window.Since.IsZero())) {
+		what := format + " input"
+		switch {
+		case stream:
+			what = "a stream"
+		case format == "":
+			what = "-delimiter records"
+		}
+		log.Printf("%s: reading %s from the start without -index or -assume-sorted seeking", filename, what)
+	}
+
+	if shardCount > 1 && seekable && scanShards(filename, f, verbs, window, channel) {
+		rc.Close()
+		return
+	}
+
+	var builder *indexBuilder
+	seeked := false
+	var resumeAt int64
+	if checkpoints != nil && seekable {
+		if resumeAt = checkpoints.ResumeOffset(filename, f); resumeAt > 0 {
+			f.Seek(resumeAt, io.SeekStart)
+			seeked = true
+			log.Printf("%s: resuming at offset %d", filename, resumeAt)
+		}
+	}
+	if *useIndex && seekable && !seeked {
+		if idx, ok := loadIndex(filename, f); ok {
+			if !window.Since.IsZero() {
+				offset := idx.SeekOffset(window.Since)
+				f.Seek(offset, io.SeekStart)
+				seeked = offset > 0
+				log.Printf("%s: skipped to offset %d using %s", filename, offset, indexPath(filename))
+			}
+		} else {
+			builder = &indexBuilder{}
+		}
+	}
+	if !seeked && builder == nil && seekable && window.Sorted && !window.Since.IsZero() {
+		offset, err := seekToTime(f, window.Since)
+		if err != nil {
+			warnf("error seeking in file: %s, err:%v", filename, err)
+		} else if offset > 0 {
+			log.Printf("%s: skipped to offset %d for -since", filename, offset)
+		}
+	}
+	var input io.Reader
+	var reader *retryReader
+	if stream {
+		input, format = sniffCompression(rc)
+	} else {
+		reader = newRetryReader(filename, f)
+		input = reader
+	}
+	input, err = decompress(input, format)
+	if err != nil {
+		fatalf("%s: %v", filename, err)
+	}
+
+	var tsFormat TimestampFormat
+	var haveFormat bool
+	// seeking for -resume, -index or -since moved where the scan starts
+	var offset int64
+	if seekable {
+		offset, _ = f.Seek(0, io.SeekCurrent)
+	}
+	split := scanRecords(recordDelimiter)
+	// the start of an unterminated last line, which may still be being
+	// written, or -1; the checkpoint ends before it
+	partialAt := int64(-1)
+	splitLines := func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := split(data, atEOF)
+		if checkpoints != nil && seekable && atEOF && advance > 0 && data[advance-1] != '\n' {
+			partialAt = offset
+			if checkpoints.resume {
+				// runs resuming from the checkpoint read it once complete
+				log.Printf("%s: leaving the unterminated last line to the next -resume run", filename)
+				return 0, nil, nil
+			}
+		}
+		offset += int64(advance)
+		return advance, token, err
+	}
+	var mapped []byte
+	if *useMmap && seekable {
+		if mapped, err = mapFile(f); err != nil {
+			log.Printf("%s: %v, reading it instead", filename, err)
+		}
+	}
+	var scanner lineScanner
+	if mapped != nil {
+		defer unmapFile(mapped)
+		// a mapped file truncated while scanning faults instead of failing
+		// a read
+		defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+		defer func() {
+			if r := recover(); r != nil {
+				if _, fault := r.(interface{ Addr() uintptr }); !fault {
+					panic(r)
+				}
+				warnf("error reading file: %s, truncated or unreadable while mapped; results stop after %d bytes", filename, offset)
+			}
+		}()
+		scanner = newMappedScanner(mapped, offset, splitLines)
+	} else {
+		s := bufio.NewScanner(input)
+		s.Buffer(make([]byte, BuffSize), BuffSize)
+		s.Split(splitLines)
+		scanner = s
+	}
 
-	f, _ := os.Open(filename)
-	defer f.Close()
-	buff := make([]byte, BuffSize)
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(buff, len(buff))
+	// nextLine returns the next line and its offset, or with -multiline
+	// the next record
+	var joiner *recordJoiner
+	if *multiline != "" {
+		if joiner, err = newRecordJoiner(*multiline); err != nil {
+			fatal(err)
+		}
+	}
+	pos := offset
+	nextLine := func() (string, int64, bool) {
+		for scanner.Scan() {
+			lineStart := pos
+			pos = offset
+			if joiner == nil {
+				return scanner.Text(), lineStart, true
+			}
+			if record, at, ok := joiner.Add(scanner.Text(), lineStart); ok {
+				return record, at, true
+			}
+		}
+		if joiner != nil {
+			return joiner.Flush()
+		}
+		return "", 0, false
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		for _, verb := range verbs.Verbs {
-			if strings.Contains(line, verb) {
-				channel <- LineMatch{line, verb}
+	start := stageTimes.Now()
+	complete := true
+	var stoppedAt int64
+	header := *lineFormat == "csv" && *csvHeader
+	for line, lineStart, ok := nextLine(); ok; line, lineStart, ok = nextLine() {
+		start = stageTimes.Since(StageRead, start)
+		if header {
+			header = false
+			if err := setCSVHeader(filename, line); err != nil {
+				fatal(err)
+			}
+			continue
+		}
+		obj := decodeLine(line)
+		if window.Active() || builder != nil {
+			if !haveFormat {
+				tsFormat, haveFormat = detectTimestampFormat(timeSubject(line, obj))
+			}
+			ts, ok := tsFormat.Parse(timeSubject(line, obj))
+			ok = ok && haveFormat
+			if !ok && *strict {
+				fatalf("%s: no timestamp found in line at offset %d: %s", filename, lineStart, line)
+			}
+			if builder != nil {
+				builder.Add(lineStart, ts, ok)
+			}
+			if window.Active() {
+				if !ok || ts.Before(window.Since) {
+					continue
+				}
+				if !window.Until.IsZero() && !ts.Before(window.Until) {
+					if window.Sorted && builder == nil {
+						complete, stoppedAt = false, lineStart
+						break
+					}
+					continue
+				}
 			}
 		}
+		if !sampled() {
+			continue
+		}
+		sendMatches(line, obj, filename, verbs, mapped != nil, channel)
+		start = stageTimes.Since(StageMatch, start)
+	}
+	if c, ok := input.(io.Closer); ok && format != "" {
+		// stops a decompressing command when -until ended the scan early
+		c.Close()
 	}
 	if err := scanner.Err(); err != nil {
-		log.Printf("error reading file: %s, err:%v", filename, err)
+		warnf("error reading file: %s, err:%v; results stop after %d bytes", filename, err, offset)
+	} else {
+		if builder != nil && complete {
+			f, _ := reader.File()
+			if err := builder.Finish(filename, f); err != nil {
+				warnf("error writing index: %s, err:%v", indexPath(filename), err)
+			}
+		}
+		if checkpoints != nil && seekable {
+			if !complete {
+				offset = stoppedAt
+			}
+			if partialAt >= 0 && partialAt < offset {
+				offset = partialAt
+			}
+			f, _ := reader.File()
+			if err := checkpoints.Set(filename, f, offset); err != nil {
+				warnf("error saving the offset of %s: %v", filename, err)
+			}
+		}
+	}
+	if stream {
+		if err := rc.Close(); err != nil {
+			warnf("error reading %s: %v", filename, err)
+		}
+		return
+	}
+	reader.Close()
+	if reader.Recovered > 0 {
+		log.Printf("%s: recovered from %d transient read errors", filename, reader.Recovered)
+	}
+}
+
+// sendMatches sends line to channel once for each verb it matches. A line
+// viewing a -mmap mapping is copied first, so only matched lines are. obj
+// is the decoded line, passed on for reading its other fields.
+func sendMatches(line string, obj jsonObject, filename string, verbs Verbs, mapped bool, channel chan LineMatch) {
+	subject := verbSubject(line, obj)
+	for i, verb := range verbs.Verbs {
+		if verbs.Matchers[i].Match(subject) {
+			if mapped {
+				line, mapped = strings.Clone(line), false
+			}
+			channel <- LineMatch{line, verb, filename, obj}
+		}
 	}
-	close(channel)
 }
 
-func processLines(channel chan LineMatch) AggregatedValues {
+func processLines(channel chan LineMatch, grouping *Grouping) AggregatedValues {
 
 	values := AggregatedValues{
 		Values: make([]float32, 0),
 		Counts: make(map[string]int),
 	}
+	if grouping != nil {
+		values.Groups = make(map[string]*AggregatedValues)
+	}
+	split := *statusKey != "" && *splitStatus
+	if split {
+		values.Classes = make(map[string]*AggregatedValues)
+	}
+	if *tenantKey != "" {
+		values.Tenants = make(map[string]*AggregatedValues)
+	}
+	if joinLog != nil {
+		values.Joined = make(map[string]*AggregatedValues)
+	}
+	if budget != nil {
+		values.Verbs = make(map[string]*AggregatedValues)
+	}
+	if canaryMatcher != nil || *variantKey != "" {
+		values.Variants = make(map[string]*AggregatedValues)
+	}
+
+	var stamps *arrivalTracker
+	if *interArrival || *gapThreshold > 0 {
+		stamps = newArrivalTracker()
+	}
+	var gaps *gapDetector
+	if *gapThreshold > 0 {
+		gaps = &gapDetector{min: *gapThreshold}
+	}
 
+	var keys []string
 	for lineMatch := range channel {
-		processLine(lineMatch.Line, lineMatch.Verb, &values)
+		start := stageTimes.Now()
+		var val float32
+		var err error
+		var ts time.Time
+		ok := true
+		if stamps != nil {
+			// only -inter-arrival needs a timestamp; -gap skips lines without one
+			if ts, err = stamps.Time(lineMatch.Line, lineMatch.Object); !*interArrival {
+				err = nil
+			}
+		}
+		switch {
+		case err != nil:
+		case *interArrival:
+			val, ok = stamps.Gap(lineMatch.Verb, ts)
+		default:
+			val, err = parseValue(lineMatch.Line, lineMatch.Object)
+			val *= valueScale
+		}
+		start = stageTimes.Since(StageParse, start)
+		if gaps != nil && !ts.IsZero() {
+			gaps.Add(ts)
+		}
+		if err != nil {
+			warnf("%v", anonymizeError(err))
+		} else if ok {
+			if grouping != nil {
+				keys = grouping.Keys(keys[:0], lineMatch)
+			}
+			addValue(val, lineMatch.Verb, keys, &values)
+			if split {
+				addToGroup(values.Classes, statusClass(lineMatch.Line, lineMatch.Object), val)
+			}
+			if values.Tenants != nil {
+				addToGroup(values.Tenants, tenantOf(lineMatch.Line, lineMatch.Object), val)
+			}
+			if values.Verbs != nil {
+				addToGroup(values.Verbs, lineMatch.Verb, val)
+			}
+			if values.Variants != nil {
+				if variant, ok := variantOf(lineMatch.Line, lineMatch.Object); ok {
+					addToGroup(values.Variants, variant, val)
+				}
+			}
+			if joinLog != nil {
+				if other, ok := joinLog.Match(lineMatch.Line, lineMatch.Object); ok {
+					addToGroup(values.Joined, "total", val)
+					addToGroup(values.Joined, "joined", other)
+					addToGroup(values.Joined, "rest", val-other)
+				} else {
+					values.Unjoined++
+				}
+			}
+		}
+		stageTimes.Since(StageAggregate, start)
+	}
+	if gaps != nil {
+		values.Gaps = gaps.Gaps
 	}
 	return values
 }
 
-// extract a float from the last field in this line
-func processLine(line string, verb string, values *AggregatedValues) {
+// extract a float from the last field in this line, or the -value-key or
+// -value-field one
+func parseValue(line string, obj jsonObject) (float32, error) {
 	// TODO: allow for regexp to find the float
-	lastSpace := strings.LastIndexByte(line, ' ')
-	floatStr := line[lastSpace+1:]
+	var floatStr string
+	switch {
+	case *valueKey != "":
+		floatStr, _ = lineField(line, obj, *valueKey)
+	case *valueField == 0:
+		lastSpace := strings.LastIndexByte(line, ' ')
+		floatStr = line[lastSpace+1:]
+	default:
+		floatStr, _ = nthField(line, *valueField)
+	}
 	f, err := strconv.ParseFloat(floatStr, 32)
 	if err != nil {
-		log.Printf("no float:%s, err: %v", floatStr, err)
-		return
+		return 0, &ParseError{Line: line, Field: floatStr, Err: err}
+	}
+	return float32(f), nil
+}
+
+// nthField returns the n'th (1-based) whitespace-separated field of line,
+// counting from the end if n is negative.
+func nthField(line string, n int) (string, bool) {
+	fields := strings.Fields(line)
+	i := n - 1
+	if n < 0 {
+		i = len(fields) + n
 	}
-	val := float32(f)
+	if i < 0 || i >= len(fields) {
+		return "", false
+	}
+	return fields[i], true
+}
+
+func addValue(val float32, verb string, groups []string, values *AggregatedValues) {
 	values.Values = append(values.Values, val)
 	values.Accum += val
+	if isTooFast(verb, val) {
+		if values.TooFast == nil {
+			values.TooFast = make(map[string]int)
+		}
+		values.TooFast[verb]++
+	}
 	_, ok := values.Counts[verb]
 	if !ok {
 		values.Counts[verb] = 1
 	} else {
 		values.Counts[verb]++
 	}
+	for _, name := range groups {
+		addToGroup(values.Groups, name, val)
+	}
+}
+
+func addToGroup(groups map[string]*AggregatedValues, name string, val float32) {
+	group, ok := groups[name]
+	if !ok {
+		group = &AggregatedValues{}
+		groups[name] = group
+	}
+	group.Values = append(group.Values, val)
+	group.Accum += val
 }
 
 func computePercentiles(values AggregatedValues, percentiles []int) PercentileValues {
@@ -148,17 +1082,39 @@ func computePercentiles(values AggregatedValues, percentiles []int) PercentileVa
 
 	values.Values.Sort()
 	count := len(values.Values)
+	if count == 0 {
+		result := PercentileValues{
+			Percentiles: make(map[int]float32, len(percentiles)),
+			Average:     -1,
+			Min:         -1,
+			Max:         -1,
+		}
+		for _, percent := range percentiles {
+			result.Percentiles[percent] = -1
+		}
+		for range underThresholds {
+			result.Under = append(result.Under, -1)
+		}
+		for range lowTail {
+			result.LowTail = append(result.LowTail, -1)
+		}
+		return result
+	}
 	result := PercentileValues{
 		Percentiles: make(map[int]float32, len(percentiles)),
 		Average:     values.Accum / float32(count),
 		Min:         values.Values[0],
 		Max:         values.Values[count-1],
-		Count:       count,
+		Count:       scaleCount(count),
 	}
 
 	for _, percent := range percentiles {
 		result.Percentiles[percent] = f(values.Values, percent)
 	}
+	result.Under = shareUnder(values.Values, underThresholds)
+	for _, p := range lowTail {
+		result.LowTail = append(result.LowTail, lowPercentile(values.Values, p))
+	}
 
 	return result
 }
@@ -173,8 +1129,15 @@ func printPercentiles(values PercentileValues) {
 	sort.Ints(keys)
 	summary := fmt.Sprintf("count: %d,    min: %.3f,    avg: %.3f,    max: %.3f\n",
 		values.Count, values.Min, values.Average, values.Max)
+	for i, p := range lowTail {
+		summary += fmt.Sprintf("%s: %.3f,    ", formatLowTail(p), values.LowTail[i])
+	}
 	for _, k := range keys {
-		summary += fmt.Sprintf("P%d%%: %.3f,    ", k, values.Percentiles[k])
+		cell := fmt.Sprintf("%.3f", values.Percentiles[k])
+		summary += fmt.Sprintf("P%d%%: %s,    ", k, colorize(values.Percentiles[k], cell))
+	}
+	for i, t := range underThresholds {
+		summary += fmt.Sprintf("<=%s: %s,    ", formatThreshold(t), formatShare(values.Under[i]))
 	}
 	log.Print(summary)
 }
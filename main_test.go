@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// checkGolden compares got against testdata/<name>, rewriting it with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// captureLog redirects the standard logger, without timestamps, for the
+// duration of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	flags := log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	})
+	return &buf
+}
+
+func TestParseVerbs(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    []string
+		wantErr bool
+	}{
+		{"GET", []string{"GET"}, false},
+		{"GET,POST", []string{"GET", "POST"}, false},
+		{",GET,,POST,", []string{"GET", "POST"}, false},
+		{"GET /api,POST", []string{"GET /api", "POST"}, false},
+		{"", nil, true},
+		{",,", nil, true},
+	}
+	for _, tt := range tests {
+		verbs, err := parseVerbs(tt.arg)
+		if tt.wantErr {
+			var cerr *ConfigError
+			if !errors.As(err, &cerr) {
+				t.Errorf("parseVerbs(%q) err = %v, want ConfigError", tt.arg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseVerbs(%q) unexpected err: %v", tt.arg, err)
+			continue
+		}
+		if !reflect.DeepEqual(verbs.Verbs, tt.want) {
+			t.Errorf("parseVerbs(%q) = %v, want %v", tt.arg, verbs.Verbs, tt.want)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    float32
+		wantErr bool
+	}{
+		{"GET /x 12.5", 12.5, false},
+		{"GET /x 12", 12, false},
+		{"GET /x -3", -3, false},
+		{"GET /x 1e3", 1000, false},
+		{"42", 42, false},
+		{"GET /x 12.5 ", 0, true},
+		{"GET /x -", 0, true},
+		{"GET /x 12ms", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseValue(tt.line, nil)
+		if tt.wantErr {
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Errorf("parseValue(%q) err = %v, want ParseError", tt.line, err)
+			} else if perr.Line != tt.line {
+				t.Errorf("ParseError.Line = %q, want %q", perr.Line, tt.line)
+			}
+			var numErr *strconv.NumError
+			if !errors.As(err, &numErr) {
+				t.Errorf("parseValue(%q) err does not wrap strconv.NumError", tt.line)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseValue(%q) = %v, %v, want %v", tt.line, got, err, tt.want)
+		}
+	}
+}
+
+func TestFilterValues(t *testing.T) {
+	verbs, _ := parseVerbs("GET,POST")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("testdata/access.log", verbs, TimeWindow{}, c)
+
+	counts := make(map[string]int)
+	for m := range c {
+		counts[m.Verb]++
+	}
+	want := map[string]int{"GET": 5, "POST": 2}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("matched %v, want %v", counts, want)
+	}
+}
+
+func TestProcessLines(t *testing.T) {
+	captureLog(t)
+	c := make(chan LineMatch, 4)
+	c <- LineMatch{Line: "GET /a 10", Verb: "GET"}
+	c <- LineMatch{Line: "GET /b 30", Verb: "GET"}
+	c <- LineMatch{Line: "POST /c 20", Verb: "POST"}
+	c <- LineMatch{Line: "POST /d oops", Verb: "POST"}
+	close(c)
+
+	values := processLines(c, nil)
+	if want := (Float32Slice{10, 30, 20}); !reflect.DeepEqual(values.Values, want) {
+		t.Errorf("Values = %v, want %v", values.Values, want)
+	}
+	if want := map[string]int{"GET": 2, "POST": 1}; !reflect.DeepEqual(values.Counts, want) {
+		t.Errorf("Counts = %v, want %v", values.Counts, want)
+	}
+	if values.Accum != 60 {
+		t.Errorf("Accum = %v, want 60", values.Accum)
+	}
+}
+
+func TestComputePercentiles(t *testing.T) {
+	seq := func(n int) Float32Slice {
+		s := make(Float32Slice, n)
+		for i := range s {
+			s[i] = float32(n - i)
+		}
+		return s
+	}
+	sum := func(s Float32Slice) (total float32) {
+		for _, v := range s {
+			total += v
+		}
+		return total
+	}
+
+	tests := []struct {
+		name   string
+		values Float32Slice
+		want   map[int]float32
+		min    float32
+		max    float32
+	}{
+		{"single", Float32Slice{7}, map[int]float32{10: 7, 50: 7, 100: 7}, 7, 7},
+		{"two", Float32Slice{2, 1}, map[int]float32{10: 1, 50: 2, 99: 2, 100: 2}, 1, 2},
+		{"hundred", seq(100), map[int]float32{10: 11, 50: 51, 90: 91, 99: 100, 100: 100}, 1, 100},
+		{"ten", seq(10), map[int]float32{10: 2, 50: 6, 90: 10, 99: 10, 100: 10}, 1, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var percentiles []int
+			for p := range tt.want {
+				percentiles = append(percentiles, p)
+			}
+			values := AggregatedValues{Values: tt.values, Accum: sum(tt.values)}
+			got := computePercentiles(values, percentiles)
+			if !reflect.DeepEqual(got.Percentiles, tt.want) {
+				t.Errorf("Percentiles = %v, want %v", got.Percentiles, tt.want)
+			}
+			if got.Count != len(tt.values) || got.Min != tt.min || got.Max != tt.max {
+				t.Errorf("count/min/max = %d/%v/%v, want %d/%v/%v",
+					got.Count, got.Min, got.Max, len(tt.values), tt.min, tt.max)
+			}
+			if want := sum(tt.values) / float32(len(tt.values)); got.Average != want {
+				t.Errorf("Average = %v, want %v", got.Average, want)
+			}
+		})
+	}
+}
+
+func TestComputePercentilesEmpty(t *testing.T) {
+	got := computePercentiles(AggregatedValues{}, []int{50, 99})
+	want := PercentileValues{Percentiles: map[int]float32{50: -1, 99: -1}, Average: -1, Min: -1, Max: -1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("computePercentiles(empty) = %+v, want %+v", got, want)
+	}
+}
+
+func TestPrintPercentilesGolden(t *testing.T) {
+	buf := captureLog(t)
+	verbs, _ := parseVerbs("GET,POST,DELETE")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("testdata/access.log", verbs, TimeWindow{}, c)
+	values := processLines(c, nil)
+	buf.Reset()
+
+	printPercentiles(computePercentiles(values, PERCENTILES[:]))
+	checkGolden(t, "summary.golden", buf.Bytes())
+}
+
+func FuzzParseValue(f *testing.F) {
+	for _, seed := range []string{"GET /x 12.5", "", " ", "x -", "1e40", "a NaN"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		_, err := parseValue(line, nil)
+		if err != nil {
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("parseValue(%q) returned %T, want *ParseError", line, err)
+			}
+		}
+	})
+}
+
+func FuzzParseVerbs(f *testing.F) {
+	for _, seed := range []string{"GET", "GET,POST", ",", "", `"a,b" AND NOT (c OR ~"d+")`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, arg string) {
+		verbs, err := parseVerbs(arg)
+		if err != nil {
+			return
+		}
+		if len(verbs.Matchers) != len(verbs.Verbs) {
+			t.Fatalf("parseVerbs(%q) returned %d matchers for %d verbs", arg, len(verbs.Matchers), len(verbs.Verbs))
+		}
+		for _, v := range verbs.Verbs {
+			if v == "" {
+				t.Fatalf("parseVerbs(%q) produced an empty verb", arg)
+			}
+		}
+	})
+}
+
+func TestWriteMarkdownGolden(t *testing.T) {
+	captureLog(t)
+	verbs, _ := parseVerbs("GET,POST,DELETE")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("testdata/access.log", verbs, TimeWindow{}, c)
+	values := processLines(c, groupByVerb)
+	summary := computePercentiles(values, PERCENTILES[:])
+	groups := computeGroupPercentiles(values, PERCENTILES[:])
+	sortGroups(groups, SortKey{Field: "name"}, false, nil)
+
+	var buf bytes.Buffer
+	writeMarkdown(&buf, summary, "verb", groups, PERCENTILES[:])
+	checkGolden(t, "summary.md.golden", buf.Bytes())
+}
+
+func TestReportTemplate(t *testing.T) {
+	tmpl, err := loadReportTemplate("testdata/oneline.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Report{
+		File:    "access.log",
+		Summary: PercentileValues{Count: 3, Percentiles: map[int]float32{50: 12.25, 99: 40}},
+		Groups: []GroupPercentiles{
+			{"GET", PercentileValues{Percentiles: map[int]float32{99: 30}}},
+			{"POST", PercentileValues{Percentiles: map[int]float32{99: 40}}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	want := "access.log: 3 requests, p50=12.2 p99=40.0\nGET=30.0 POST=40.0 \n"
+	if got := buf.String(); got != want {
+		t.Errorf("template output = %q, want %q", got, want)
+	}
+
+	var cerr *ConfigError
+	if _, err := loadReportTemplate("testdata/missing.tmpl"); !errors.As(err, &cerr) {
+		t.Errorf("missing template err = %v, want ConfigError", err)
+	}
+}
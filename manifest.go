@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ManifestEntry identifies the bytes of one input file that a report was
+// computed from.
+type ManifestEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	SHA256  string    `json:"sha256"`
+}
+
+// Manifest is written by -manifest next to the report.
+type Manifest struct {
+	Created time.Time       `json:"created"`
+	Verbs   []string        `json:"verbs"`
+	Files   []ManifestEntry `json:"files"`
+}
+
+// statManifestEntry records the size and mtime of path before it is
+// processed, so that bytes appended during the run are left out of the
+// hash.
+func statManifestEntry(path string) (ManifestEntry, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	return ManifestEntry{Path: path, Size: fi.Size(), ModTime: fi.ModTime().UTC()}, nil
+}
+
+// hash fills in the SHA-256 of the first e.Size bytes of the file.
+func (e *ManifestEntry) hash() error {
+	f, err := os.Open(e.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.CopyN(h, f, e.Size)
+	if err != nil {
+		return fmt.Errorf("hashing %s: %v after %d bytes", e.Path, err, n)
+	}
+	e.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+func writeManifest(path string, m Manifest) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
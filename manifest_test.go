@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := statManifestEntry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bytes appended after the stat are not part of the analyzed input
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("def")
+	f.Close()
+	if err := entry.hash(); err != nil {
+		t.Fatal(err)
+	}
+	const sha256abc = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if entry.Size != 3 || entry.SHA256 != sha256abc {
+		t.Errorf("entry = %+v, want size 3 and the SHA-256 of \"abc\"", entry)
+	}
+
+	out := filepath.Join(t.TempDir(), "manifest.json")
+	if err := writeManifest(out, Manifest{Verbs: []string{"GET"}, Files: []ManifestEntry{entry}}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(out)
+	var got Manifest
+	if err := json.Unmarshal(data, &got); err != nil || len(got.Files) != 1 || got.Files[0].SHA256 != sha256abc {
+		t.Errorf("manifest = %s, err %v", data, err)
+	}
+}
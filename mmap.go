@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"unsafe"
+)
+
+// lineScanner is the part of bufio.Scanner scanFile reads lines with.
+type lineScanner interface {
+	Scan() bool
+	Text() string
+	Err() error
+}
+
+// mappedScanner splits a memory-mapped file into lines like bufio.Scanner,
+// but without copying them: Text returns a view of the mapping, which is
+// only valid until it is unmapped. Lines kept longer must be copied.
+type mappedScanner struct {
+	data  []byte
+	pos   int
+	split bufio.SplitFunc
+	token []byte
+	err   error
+}
+
+func newMappedScanner(data []byte, offset int64, split bufio.SplitFunc) *mappedScanner {
+	return &mappedScanner{data: data, pos: int(offset), split: split}
+}
+
+func (s *mappedScanner) Scan() bool {
+	for s.err == nil && s.pos < len(s.data) {
+		// all of the file is there, so every call is at EOF
+		advance, token, err := s.split(s.data[s.pos:], true)
+		if err != nil {
+			s.err = err
+			return false
+		}
+		if advance == 0 && token == nil {
+			// the split leaves the rest, e.g. a line still being written
+			return false
+		}
+		s.pos += advance
+		if token != nil {
+			s.token = token
+			return true
+		}
+	}
+	return false
+}
+
+func (s *mappedScanner) Text() string {
+	return unsafe.String(unsafe.SliceData(s.token), len(s.token))
+}
+
+func (s *mappedScanner) Err() error { return s.err }
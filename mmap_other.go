@@ -0,0 +1,17 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// mapFile is not available on this platform; files are read instead.
+func mapFile(f *os.File) ([]byte, error) {
+	return nil, errors.New("memory mapping is not supported on this platform")
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,55 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMmap(t *testing.T) {
+	captureLog(t)
+	defer func(v bool) { *useMmap = v }(*useMmap)
+	var b strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&b, "GET /api/%d %d\n", i%7, i%100)
+	}
+	b.WriteString("\nPOST /api 5\nGET /api/last 1000")
+	path := filepath.Join(t.TempDir(), "big.log")
+	os.WriteFile(path, []byte(b.String()), 0644)
+	verbs, _ := parseVerbs("GET,/api/3")
+	read := func() AggregatedValues {
+		c := make(chan LineMatch, ChanSize)
+		go filterValues(path, verbs, TimeWindow{}, c)
+		return processLines(c, groupByVerb)
+	}
+
+	want := read()
+	*useMmap = true
+	got := read()
+	if fmt.Sprint(got.Values) != fmt.Sprint(want.Values) || fmt.Sprint(got.Counts) != fmt.Sprint(want.Counts) {
+		t.Errorf("read %d values %v mapped, want %d %v", len(got.Values), got.Counts, len(want.Values), want.Counts)
+	}
+	if n := len(got.Groups["/api/3"].Values); n != 143 {
+		t.Errorf("got %d values of /api/3, want 143", n)
+	}
+
+	os.WriteFile(path, nil, 0644)
+	if got := read(); len(got.Values) != 0 {
+		t.Errorf("read %v from an empty file", got.Values)
+	}
+}
+
+func TestMappedScanner(t *testing.T) {
+	s := newMappedScanner([]byte("skip\na\r\n\nb"), 5, scanRecords(nil))
+	var lines []string
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	if fmt.Sprintf("%q", lines) != `["a" "" "b"]` || s.Err() != nil {
+		t.Errorf("got lines %q, err %v", lines, s.Err())
+	}
+}
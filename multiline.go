@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// recordJoiner joins physical lines into records for -multiline: a line
+// that doesn't start a record, like the frames of a stack trace, is
+// appended to the one before it, separated by a space.
+type recordJoiner struct {
+	starts func(line string) bool
+	record strings.Builder
+	offset int64
+	have   bool
+}
+
+// newRecordJoiner returns the joiner for a -multiline rule: "timestamp"
+// starts a record at each line stamped like the first stamped line, and
+// anything else is a regular expression matching the lines that do.
+func newRecordJoiner(rule string) (*recordJoiner, error) {
+	if rule == "timestamp" {
+		var format TimestampFormat
+		var haveFormat bool
+		return &recordJoiner{starts: func(line string) bool {
+			subject := timeSubject(line, nil)
+			if !haveFormat {
+				format, haveFormat = detectTimestampFormat(subject)
+			}
+			_, ok := format.Parse(subject)
+			return ok && haveFormat
+		}}, nil
+	}
+	re, err := regexp.Compile(rule)
+	if err != nil {
+		return nil, &ConfigError{"multiline", fmt.Errorf("bad rule %q: %v", rule, err)}
+	}
+	return &recordJoiner{starts: re.MatchString}, nil
+}
+
+// Add adds the line at offset, and returns the previous record and its
+// offset when the line starts a new one. Lines before the first one that
+// starts a record are joined into a record too.
+func (j *recordJoiner) Add(line string, offset int64) (string, int64, bool) {
+	// starts is called on every line, to detect the timestamp format
+	if starts := j.starts(line); j.have && !starts {
+		// records are capped like lines, at the scanner's buffer size
+		if j.record.Len()+1+len(line) <= BuffSize {
+			j.record.WriteByte(' ')
+			j.record.WriteString(line)
+		}
+		return "", 0, false
+	}
+	record, at, ok := j.Flush()
+	j.record.WriteString(line)
+	j.offset, j.have = offset, true
+	return record, at, ok
+}
+
+// Flush returns the record being joined, if any, and starts over.
+func (j *recordJoiner) Flush() (string, int64, bool) {
+	if !j.have {
+		return "", 0, false
+	}
+	record := j.record.String()
+	j.record.Reset()
+	j.have = false
+	return record, j.offset, true
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecordJoiner(t *testing.T) {
+	lines := []string{
+		"continued from an earlier file",
+		"2024-03-01T10:00:00Z GET /api failed",
+		"java.lang.IllegalStateException: boom",
+		"    at com.example.Api.get(Api.java:42)",
+		"2024-03-01T10:00:01Z GET /api 12.5",
+		"2024-03-01T10:00:02Z POST /api",
+		"  wrapped 40",
+	}
+	want := []string{
+		"continued from an earlier file",
+		"2024-03-01T10:00:00Z GET /api failed java.lang.IllegalStateException: boom     at com.example.Api.get(Api.java:42)",
+		"2024-03-01T10:00:01Z GET /api 12.5",
+		"2024-03-01T10:00:02Z POST /api   wrapped 40",
+	}
+	for _, rule := range []string{"timestamp", `^\d{4}-`} {
+		j, err := newRecordJoiner(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		var offsets []int64
+		for i, line := range lines {
+			if record, at, ok := j.Add(line, int64(i)); ok {
+				got, offsets = append(got, record), append(offsets, at)
+			}
+		}
+		if record, at, ok := j.Flush(); ok {
+			got, offsets = append(got, record), append(offsets, at)
+		}
+		if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(offsets, []int64{0, 1, 4, 5}) {
+			t.Errorf("%s: records = %q at %v, want %q", rule, got, offsets, want)
+		}
+	}
+	if _, err := newRecordJoiner("(unclosed"); err == nil {
+		t.Error("bad regexp was accepted")
+	}
+}
+
+func TestMultilineInput(t *testing.T) {
+	captureLog(t)
+	defer func(rule string) { *multiline = rule }(*multiline)
+	*multiline = "timestamp"
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("2024-03-01T10:00:00Z GET /api\n  took 12.5\n2024-03-01T10:00:01Z GET /api 8\n"), 0644)
+
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues(path, verbs, TimeWindow{}, c)
+	if values := processLines(c, nil); !reflect.DeepEqual([]float32(values.Values), []float32{12.5, 8}) {
+		t.Errorf("Values = %v, want [12.5 8]", values.Values)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Network is a named address range for -group-by=network.
+type Network struct {
+	Prefix netip.Prefix
+	Name   string
+}
+
+// loadNetworks reads "<cidr> <name>" lines; blank lines and lines starting
+// with # are ignored. The result is ordered most specific first so the
+// first containing network is the longest-prefix match.
+func loadNetworks(path string) ([]Network, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &ConfigError{"networks", err}
+	}
+	defer f.Close()
+
+	var networks []Network
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, &ConfigError{"networks", fmt.Errorf("%s:%d: want \"<cidr> <name>\"", path, n)}
+		}
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			return nil, &ConfigError{"networks", fmt.Errorf("%s:%d: %v", path, n, err)}
+		}
+		networks = append(networks, Network{prefix.Masked(), fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &ConfigError{"networks", err}
+	}
+	sort.SliceStable(networks, func(i, j int) bool {
+		return networks[i].Prefix.Bits() > networks[j].Prefix.Bits()
+	})
+	return networks, nil
+}
+
+// groupByNetwork counts each line under the network containing the first
+// IP address found in it, or "other".
+func groupByNetwork(networks []Network) *Grouping {
+	return &Grouping{
+		Label: "network",
+		Keys: func(dst []string, m LineMatch) []string {
+			addr, ok := extractAddr(m.Line)
+			if !ok {
+				return append(dst, "other")
+			}
+			for _, n := range networks {
+				if n.Prefix.Contains(addr) {
+					return append(dst, n.Name)
+				}
+			}
+			return append(dst, "other")
+		},
+	}
+}
+
+// extractAddr returns the first field of line that is an IP address,
+// optionally with a port or in brackets.
+func extractAddr(line string) (netip.Addr, bool) {
+	for _, field := range strings.Fields(line) {
+		field = strings.Trim(field, `",`)
+		if ap, err := netip.ParseAddrPort(field); err == nil {
+			return ap.Addr().Unmap(), true
+		}
+		if addr, err := netip.ParseAddr(strings.Trim(field, "[]")); err == nil {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGroupByNetwork(t *testing.T) {
+	networks, err := loadNetworks("testdata/networks.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := groupByNetwork(networks)
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"10.1.2.3 - - GET /x 12", "internal"},
+		{"10.20.7.7 - - GET /x 12", "eu-pop"},
+		{"host 192.168.1.40:51234 GET /x 12", "office"},
+		{"[2001:db8::1]:443 GET /x 12", "v6-pop"},
+		{"::ffff:10.20.0.1 GET /x 12", "eu-pop"},
+		{"8.8.8.8 GET /x 12", "other"},
+		{"GET /x 12", "other"},
+	}
+	for _, tt := range tests {
+		got := g.Keys(nil, LineMatch{Line: tt.line})
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("network of %q = %v, want %s", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLoadNetworksErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.txt")
+	for _, content := range []string{"10.0.0.0/8\n", "10.0.0.0/33 x\n", "nonsense x\n"} {
+		os.WriteFile(path, []byte(content), 0644)
+		var cerr *ConfigError
+		if _, err := loadNetworks(path); !errors.As(err, &cerr) {
+			t.Errorf("loadNetworks(%q) err = %v, want ConfigError", content, err)
+		}
+	}
+}
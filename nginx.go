@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// nginxFields names the fields of the combined log format, in order. Any
+// fields logged after them, as in the common extension with
+// $request_time, are only reachable by number.
+var nginxFields = []string{"remote_addr", "ident", "remote_user", "time_local", "request",
+	"status", "body_bytes_sent", "http_referer", "http_user_agent"}
+
+// splitAccessLog splits an access log line into whitespace-separated
+// fields, keeping "quoted" and [bracketed] fields whole and without their
+// delimiters. Quotes may be escaped with a backslash, as nginx does.
+func splitAccessLog(line string) []string {
+	var fields []string
+	for i := 0; i < len(line); {
+		switch line[i] {
+		case ' ', '\t':
+			i++
+		case '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			fields = append(fields, strings.ReplaceAll(line[i+1:min(end, len(line))], `\"`, `"`))
+			i = end + 1
+		case '[':
+			end := strings.IndexByte(line[i:], ']')
+			if end < 0 {
+				end = len(line) - i
+			}
+			fields = append(fields, line[i+1:i+end])
+			i += end + 1
+		default:
+			end := strings.IndexAny(line[i:], " \t")
+			if end < 0 {
+				end = len(line) - i
+			}
+			fields = append(fields, line[i:i+end])
+			i += end
+		}
+	}
+	return fields
+}
+
+// nginxField returns the field key of a combined log format line: one of
+// nginxFields; method, path or route, taken from the request (see
+// requestField); request_time, the first field after the user agent; or a
+// 1-based field number.
+func nginxField(line, key string) (string, bool) {
+	fields := splitAccessLog(line)
+	i := -1
+	switch key {
+	case "method", "path", "route":
+		return requestField(fields, 4, key)
+	case "request_time":
+		i = len(nginxFields)
+	default:
+		for j, name := range nginxFields {
+			if name == key {
+				i = j
+			}
+		}
+		if n, err := strconv.Atoi(key); err == nil && n > 0 {
+			i = n - 1
+		}
+	}
+	if i < 0 || i >= len(fields) {
+		return "", false
+	}
+	return fields[i], true
+}
+
+// requestField returns the method, the path or the route, which is both,
+// of the HTTP request line in fields[i]. A path logged as an absolute URL
+// loses its scheme and host.
+func requestField(fields []string, i int, key string) (string, bool) {
+	if i >= len(fields) {
+		return "", false
+	}
+	parts := strings.Fields(fields[i])
+	if len(parts) < 2 {
+		return "", false
+	}
+	method, path := parts[0], parts[1]
+	if _, rest, ok := strings.Cut(path, "://"); ok {
+		path = "/"
+		if slash := strings.IndexByte(rest, '/'); slash >= 0 {
+			path = rest[slash:]
+		}
+	}
+	switch key {
+	case "method":
+		return method, true
+	case "path":
+		return path, true
+	}
+	return method + " " + path, true
+}
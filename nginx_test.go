@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestNginxField(t *testing.T) {
+	line := `10.0.0.1 - - [01/Mar/2024:10:00:02 +0000] "GET /api/users/7 HTTP/1.1" 404 0 "-" "say \"hi\"" 0.008`
+	tests := []struct {
+		key, want string
+		ok        bool
+	}{
+		{"remote_addr", "10.0.0.1", true},
+		{"time_local", "01/Mar/2024:10:00:02 +0000", true},
+		{"request", "GET /api/users/7 HTTP/1.1", true},
+		{"method", "GET", true},
+		{"path", "/api/users/7", true},
+		{"status", "404", true},
+		{"http_user_agent", `say "hi"`, true},
+		{"request_time", "0.008", true},
+		{"10", "0.008", true},
+		{"11", "", false},
+		{"upstream", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := nginxField(line, tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("nginxField(%s) = %q, %v; want %q, %v", tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNginxFormat(t *testing.T) {
+	captureLog(t)
+	defer func(format, verb, ts, value, unit string) {
+		*lineFormat, *verbKey, *timeKey, *valueKey, *inputUnit = format, verb, ts, value, unit
+		valueScale = 1
+	}(*lineFormat, *verbKey, *timeKey, *valueKey, *inputUnit)
+	*lineFormat = "nginx"
+	if err := checkLineFormat(); err != nil {
+		t.Fatal(err)
+	}
+	valueScale, _ = parseInputUnit(*inputUnit)
+
+	verbs, _ := parseVerbs("GET /api")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("testdata/nginx.log", verbs, TimeWindow{}, c)
+	values := processLines(c, nil)
+	if want := []float32{12, 8}; len(values.Values) != 2 || values.Values[0] != want[0] || values.Values[1] != want[1] {
+		t.Errorf("Values = %v, want %v ms", values.Values, want)
+	}
+}
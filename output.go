@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+var outputFormats = []string{"text", "markdown"}
+
+func init() {
+	flagChoices["output"] = outputFormats
+}
+
+func checkOutputFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return &ConfigError{"output", fmt.Errorf("unknown format %q, want one of %s",
+		format, strings.Join(outputFormats, ", "))}
+}
+
+// writeMarkdown writes the summary and, if groups is not empty, the
+// breakdown as GitHub-flavored markdown tables.
+func writeMarkdown(w io.Writer, summary PercentileValues, label string, groups []GroupPercentiles, percentiles []int) {
+	writeMarkdownGroups(w, "", []GroupPercentiles{{"", summary}}, percentiles)
+	if len(groups) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	writeMarkdownGroups(w, label, groups, percentiles)
+}
+
+// writeMarkdownGroups writes rows as a table, with their names in a first
+// column named label unless it is empty.
+func writeMarkdownGroups(w io.Writer, label string, rows []GroupPercentiles, percentiles []int) {
+	header := func(first string) {
+		cols := []string{"count", "min", "avg", "max"}
+		for _, p := range lowTail {
+			cols = append(cols, formatLowTail(p))
+		}
+		for _, p := range percentiles {
+			cols = append(cols, fmt.Sprintf("P%d%%", p))
+		}
+		for _, t := range underThresholds {
+			cols = append(cols, "&le;"+formatThreshold(t))
+		}
+		align := strings.Repeat("---:|", len(cols))
+		if first != "" {
+			fmt.Fprintf(w, "| %s ", first)
+			align = "---|" + align
+		}
+		fmt.Fprintf(w, "| %s |\n|%s\n", strings.Join(cols, " | "), align)
+	}
+	row := func(first string, v PercentileValues) {
+		if first != "" {
+			fmt.Fprintf(w, "| %s ", strings.ReplaceAll(first, "|", `\|`))
+		}
+		fmt.Fprintf(w, "| %d | %.3f | %.3f | %.3f |", v.Count, v.Min, v.Average, v.Max)
+		for _, low := range v.LowTail {
+			fmt.Fprintf(w, " %.3f |", low)
+		}
+		for _, p := range percentiles {
+			fmt.Fprintf(w, " %.3f |", v.Percentiles[p])
+		}
+		for _, share := range v.Under {
+			fmt.Fprintf(w, " %s |", formatShare(share))
+		}
+		fmt.Fprintln(w)
+	}
+
+	header(label)
+	for _, r := range rows {
+		row(r.Name, r.PercentileValues)
+	}
+}
+
+// Report is the data passed to -template. Percentiles are looked up by
+// number, e.g. {{index .Summary.Percentiles 99}}.
+type Report struct {
+	Verbs       []string
+	File        string // the input files, comma separated
+	Files       []string
+	Percentiles []int
+	Under       []float32 // -under thresholds, matching PercentileValues.Under
+	LowTail     []float64 // -low-tail percentiles, matching PercentileValues.LowTail
+	Summary     PercentileValues
+	GroupLabel  string
+	Groups      []GroupPercentiles
+	Gaps        []Gap              // with -gap
+	Classes     []GroupPercentiles // per status class, with -status-key
+	Tenants     []TenantRanking    // with -tenant-key
+	Budget      []BudgetRow        // with -budget
+	Clusters    []Cluster          // with -clusters
+	Variants    []GroupPercentiles // with -canary or -variant-key
+	Comparisons []VariantComparison
+}
+
+func loadReportTemplate(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ConfigError{"template", err}
+	}
+	tmpl, err := template.New(path).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, &ConfigError{"template", err}
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// shardCount is the number of shards -parallel scans a file in at once;
+// main sets it. With 1, files are scanned by a single reader.
+var shardCount = 1
+
+// minShardSize keeps files that are quick to read anyway from being split.
+var minShardSize int64 = 16 << 20
+
+// parallelConflict returns the first option set that needs the lines of a
+// file read in order, by one reader, or "" if there is none.
+func parallelConflict(window TimeWindow) string {
+	switch {
+	case *interArrival:
+		return "-inter-arrival"
+	case *gapThreshold > 0:
+		return "-gap"
+	case *multiline != "":
+		return "-multiline"
+	case sampler != nil:
+		return "-sample"
+	case *useIndex:
+		return "-index"
+	case window.Sorted && window.Active():
+		return "-assume-sorted"
+	case checkpoints != nil:
+		return "-state"
+	case *follow:
+		return "-follow"
+	}
+	return ""
+}
+
+// lineStartAfter returns the offset of the first line starting after
+// offset in f, or the size of f if there is none.
+func lineStartAfter(f *os.File, offset, size int64) (int64, error) {
+	buf := make([]byte, 64*1024)
+	for offset < size {
+		n, err := f.ReadAt(buf, offset)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return offset + int64(i) + 1, nil
+		}
+		offset += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return size, nil
+}
+
+// shardBounds splits f from start to its end into up to n byte ranges of
+// about the same size, each starting at a line. Range i is
+// [bounds[i], bounds[i+1]).
+func shardBounds(f *os.File, start, size int64, n int) ([]int64, error) {
+	bounds := []int64{start}
+	for i := 1; i < n; i++ {
+		target := start + (size-start)*int64(i)/int64(n)
+		offset, err := lineStartAfter(f, target-1, size)
+		if err != nil {
+			return nil, err
+		}
+		if offset > bounds[len(bounds)-1] && offset < size {
+			bounds = append(bounds, offset)
+		}
+	}
+	return append(bounds, size), nil
+}
+
+// scanShards scans the plain local file f in up to shardCount shards at
+// once, each in its own goroutine and with its own reader, and reports
+// whether it did. Files too small to split are left to scanFile.
+func scanShards(filename string, f *os.File, verbs Verbs, window TimeWindow, channel chan LineMatch) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Size()/minShardSize < 2 {
+		return false
+	}
+	size := fi.Size()
+	n := min(int64(shardCount), size/minShardSize)
+
+	start := int64(0)
+	if *lineFormat == "csv" && *csvHeader {
+		line, err := bufio.NewReader(io.NewSectionReader(f, 0, size)).ReadString('\n')
+		if err != nil && err != io.EOF {
+			warnf("error reading file: %s, err:%v", filename, err)
+			return true
+		}
+		if err := setCSVHeader(filename, strings.TrimRight(line, "\r\n")); err != nil {
+			fatal(err)
+		}
+		start = int64(len(line))
+	}
+	bounds, err := shardBounds(f, start, size, int(n))
+	if err != nil {
+		warnf("error splitting file: %s, err:%v; reading it sequentially", filename, err)
+		return false
+	}
+	log.Printf("%s: reading %d shards in parallel", filename, len(bounds)-1)
+
+	var wg sync.WaitGroup
+	times := make([]StageTimes, len(bounds)-1)
+	for i := range times {
+		times[i].Enabled = stageTimes.Enabled
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			scanShard(filename, bounds[i], bounds[i+1], verbs, window, channel, &times[i])
+		}(i)
+	}
+	wg.Wait()
+	// stageTimes is only updated by this goroutine, once the shards are done
+	for _, t := range times {
+		stageTimes.Total[StageRead] += t.Total[StageRead]
+		stageTimes.Total[StageMatch] += t.Total[StageMatch]
+	}
+	return true
+}
+
+// scanShard sends the matching lines of filename from offset from to to,
+// like scanFile does for a whole file.
+func scanShard(filename string, from, to int64, verbs Verbs, window TimeWindow, channel chan LineMatch, times *StageTimes) {
+	f, err := os.Open(filename)
+	if err == nil {
+		_, err = f.Seek(from, io.SeekStart)
+	}
+	if err != nil {
+		warnf("error reading file: %s, err:%v; skipping the shard at offset %d", filename, err, from)
+		return
+	}
+	reader := newRetryReader(filename, f)
+	defer reader.Close()
+
+	var tsFormat TimestampFormat
+	var haveFormat bool
+	scanner := bufio.NewScanner(io.LimitReader(reader, to-from))
+	scanner.Buffer(make([]byte, BuffSize), BuffSize)
+	start := times.Now()
+	for scanner.Scan() {
+		start = times.Since(StageRead, start)
+		line := scanner.Text()
+		obj := decodeLine(line)
+		if window.Active() {
+			if !haveFormat {
+				tsFormat, haveFormat = detectTimestampFormat(timeSubject(line, obj))
+			}
+			ts, ok := tsFormat.Parse(timeSubject(line, obj))
+			if !ok || !haveFormat {
+				if *strict {
+					fatalf("%s: no timestamp found in line of the shard at offset %d: %s", filename, from, line)
+				}
+				continue
+			}
+			if ts.Before(window.Since) || (!window.Until.IsZero() && !ts.Before(window.Until)) {
+				continue
+			}
+		}
+		sendMatches(line, obj, filename, verbs, false, channel)
+		start = times.Since(StageMatch, start)
+	}
+	if err := scanner.Err(); err != nil {
+		warnf("error reading file: %s, err:%v; results stop early in the shard at offset %d", filename, err, from)
+	}
+	if reader.Recovered > 0 {
+		log.Printf("%s: recovered from %d transient read errors", filename, reader.Recovered)
+	}
+}
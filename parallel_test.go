@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParallel(t *testing.T) {
+	captureLog(t)
+	defer func(n int, size int64) { shardCount, minShardSize = n, size }(shardCount, minShardSize)
+	var b strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&b, "GET /api/%d %d\n", i%7, i%100)
+	}
+	b.WriteString("GET /api/last 1000")
+	path := filepath.Join(t.TempDir(), "big.log")
+	os.WriteFile(path, []byte(b.String()), 0644)
+	verbs, _ := parseVerbs("GET,/api/3")
+	read := func() AggregatedValues {
+		c := make(chan LineMatch, ChanSize)
+		go filterValues(path, verbs, TimeWindow{}, c)
+		return processLines(c, nil)
+	}
+
+	want := read()
+	shardCount, minShardSize = 8, 1000
+	got := read()
+	if len(got.Values) != len(want.Values) || got.Counts["GET"] != want.Counts["GET"] || got.Counts["/api/3"] != want.Counts["/api/3"] {
+		t.Fatalf("read %d values %v in shards, want %d %v", len(got.Values), got.Counts, len(want.Values), want.Counts)
+	}
+	if g, w := computePercentiles(got, PERCENTILES[:]), computePercentiles(want, PERCENTILES[:]); fmt.Sprint(g) != fmt.Sprint(w) {
+		t.Errorf("percentiles in shards %v, want %v", g, w)
+	}
+}
+
+func TestShardBounds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.log")
+	os.WriteFile(path, []byte("aaaa\nbb\ncccccccccc\nd\n"), 0644)
+	f, _ := os.Open(path)
+	defer f.Close()
+	bounds, err := shardBounds(f, 0, 21, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// targets 5, 10 and 15: 5 starts a line, 10 and 15 are both in the
+	// line that ends before "d"
+	if fmt.Sprint(bounds) != "[0 5 19 21]" {
+		t.Errorf("got bounds %v, want [0 5 19 21]", bounds)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// groupByPathPrefix counts each line in every prefix of its URL path, down
+// to depth segments, so "/api/users/7" is counted in "/api", "/api/users"
+// and "/api/users/{id}".
+func groupByPathPrefix(depth int) *Grouping {
+	return &Grouping{
+		Label: "path",
+		Keys: func(dst []string, m LineMatch) []string {
+			path, ok := extractPath(m.Line)
+			if !ok {
+				return dst
+			}
+			var prefix strings.Builder
+			for i, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+				if i == depth || segment == "" {
+					break
+				}
+				prefix.WriteByte('/')
+				prefix.WriteString(normalizeSegment(segment))
+				dst = append(dst, prefix.String())
+			}
+			return dst
+		},
+	}
+}
+
+// extractPath returns the first field of line that looks like a URL path,
+// without its query string.
+func extractPath(line string) (string, bool) {
+	for _, field := range strings.Fields(line) {
+		if strings.HasPrefix(field, "/") {
+			if i := strings.IndexAny(field, "?#"); i >= 0 {
+				field = field[:i]
+			}
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// normalizeSegment collapses segments that look like identifiers (numbers,
+// UUIDs, long hex strings) into "{id}" so they share a tree node.
+func normalizeSegment(segment string) string {
+	if _, err := strconv.ParseUint(segment, 10, 64); err == nil {
+		return "{id}"
+	}
+	if len(segment) >= 8 {
+		hex, digits := true, false
+		for _, c := range segment {
+			switch {
+			case c >= '0' && c <= '9':
+				digits = true
+			case c >= 'a' && c <= 'f', c >= 'A' && c <= 'F', c == '-':
+			default:
+				hex = false
+			}
+		}
+		if hex && digits {
+			return "{id}"
+		}
+	}
+	return segment
+}
+
+// sortPathTree orders groups so that each path directly follows its parent.
+func sortPathTree(groups []GroupPercentiles) {
+	sort.Slice(groups, func(i, j int) bool {
+		a := strings.Split(groups[i].Name, "/")
+		b := strings.Split(groups[j].Name, "/")
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+}
+
+func printPathTree(groups []GroupPercentiles) {
+	width := len("path")
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		depth := strings.Count(g.Name, "/") - 1
+		names[i] = strings.Repeat("  ", depth) + g.Name[strings.LastIndexByte(g.Name, '/'):]
+		width = max(width, len(names[i]))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%-*s    %-8s    %-8s    %s\n", width, "path", "count", "avg", "P99%")
+	for i, g := range groups {
+		p99 := g.Percentiles[99]
+		fmt.Fprintf(&b, "%-*s    %-8d    %-8.3f    %s\n", width, names[i], g.Count, g.Average,
+			colorize(p99, fmt.Sprintf("%.3f", p99)))
+	}
+	log.Print(b.String())
+}
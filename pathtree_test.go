@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractPath(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+		ok   bool
+	}{
+		{"host GET /api/users 200 12", "/api/users", true},
+		{`1.2.3.4 - - "GET /api/users?id=7 HTTP/1.1" 200 3`, "/api/users", true},
+		{"host GET / 200 1", "/", true},
+		{"host GET 200 1", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := extractPath(tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("extractPath(%q) = %q, %v, want %q, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeSegment(t *testing.T) {
+	tests := map[string]string{
+		"users":                                "users",
+		"42":                                   "{id}",
+		"3f2504e0-4f89-11d3-9a0c-0305e82c3301": "{id}",
+		"deadbeef01":                           "{id}",
+		"deadbeef":                             "deadbeef",
+		"v2":                                   "v2",
+	}
+	for in, want := range tests {
+		if got := normalizeSegment(in); got != want {
+			t.Errorf("normalizeSegment(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGroupByPathPrefix(t *testing.T) {
+	g := groupByPathPrefix(2)
+	got := g.Keys(nil, LineMatch{Line: "GET /api/users/7/orders 200 12"})
+	if want := []string{"/api", "/api/users"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("depth 2 keys = %v, want %v", got, want)
+	}
+	g = groupByPathPrefix(5)
+	got = g.Keys(nil, LineMatch{Line: "GET /api/users/7 200 12"})
+	if want := []string{"/api", "/api/users", "/api/users/{id}"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("depth 5 keys = %v, want %v", got, want)
+	}
+}
+
+func TestSortPathTree(t *testing.T) {
+	var groups []GroupPercentiles
+	for _, name := range []string{"/api/users", "/api-v2", "/api", "/api/orders", "/api/users/{id}"} {
+		groups = append(groups, GroupPercentiles{Name: name})
+	}
+	sortPathTree(groups)
+	var got []string
+	for _, g := range groups {
+		got = append(got, g.Name)
+	}
+	want := []string{"/api", "/api/orders", "/api/users", "/api/users/{id}", "/api-v2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortPathTree = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"strings"
+)
+
+func init() {
+	registerSource(&Source{Scheme: "unix", Open: openUnixListener})
+}
+
+// isPipe reports whether name is a local named pipe or unix domain socket,
+// which another process writes into as it goes.
+func isPipe(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && fi.Mode()&(fs.ModeNamedPipe|fs.ModeSocket) != 0
+}
+
+// openPipe opens the named pipe name, waiting for a writer, or connects to
+// the unix domain socket name. The input ends when the last writer closes
+// the pipe, or the other end the connection.
+func openPipe(name string) (io.ReadCloser, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode()&fs.ModeSocket != 0 {
+		return net.Dial("unix", name)
+	}
+	return os.Open(name)
+}
+
+// openUnixListener creates a unix domain socket at the path of a
+// unix://<path> URI and reads the first connection to it until it is
+// closed, e.g. by "producer | nc -U <path>". The socket is removed then.
+func openUnixListener(uri string) (io.ReadCloser, error) {
+	path := strings.TrimPrefix(uri, "unix://")
+	if path == "" {
+		return nil, &ConfigError{"input", fmt.Errorf("%s: no socket path", uri)}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+	log.Printf("listening on %s, waiting for a connection", path)
+	return l.Accept()
+}
@@ -0,0 +1,72 @@
+//go:build unix
+
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUnixSocket(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "sock")
+
+	// unix:// listens for a writer
+	done := make(chan []byte)
+	go func() {
+		r, err := openInput("unix://" + name)
+		if err != nil {
+			t.Error(err)
+			close(done)
+			return
+		}
+		defer r.Close()
+		got, _ := io.ReadAll(r)
+		done <- got
+	}()
+	var c net.Conn
+	for i := 0; i < 100; i++ {
+		var err error
+		if c, err = net.Dial("unix", name); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c == nil {
+		t.Fatal("no listener on", name)
+	}
+	c.Write([]byte("GET 3\n"))
+	c.Close()
+	if got := <-done; string(got) != "GET 3\n" {
+		t.Errorf("listener read %q", got)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("socket left behind: %v", err)
+	}
+
+	// a socket path connects to its server
+	l, err := net.Listen("unix", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		c.Write([]byte("GET 4\n"))
+		c.Close()
+	}()
+	r, err := openInput(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got, _ := io.ReadAll(r); string(got) != "GET 4\n" {
+		t.Errorf("connection read %q", got)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// retryReader reads a file and, when a read fails with an error that
+// network filesystems return transiently (EIO, ESTALE), reopens the file by
+// name, seeks back to the last good offset and tries again.
+type retryReader struct {
+	name    string
+	r       io.ReadSeekCloser
+	open    func(name string) (io.ReadSeekCloser, error)
+	offset  int64
+	retries int
+	delay   time.Duration
+
+	Recovered int // read errors that a retry got past
+}
+
+func newRetryReader(name string, f *os.File) *retryReader {
+	offset, _ := f.Seek(0, io.SeekCurrent)
+	return &retryReader{
+		name:    name,
+		r:       f,
+		open:    func(name string) (io.ReadSeekCloser, error) { return os.Open(name) },
+		offset:  offset,
+		retries: *readRetries,
+		delay:   *retryDelay,
+	}
+}
+
+func isTransientReadError(err error) bool {
+	for _, transient := range transientReadErrors {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *retryReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.offset += int64(n)
+	if err == nil || err == io.EOF || !isTransientReadError(err) {
+		return n, err
+	}
+	if n > 0 {
+		// hand over what was read; the next call hits the error again
+		return n, nil
+	}
+	for attempt := 1; attempt <= r.retries; attempt++ {
+		log.Printf("error reading file: %s at offset %d, err:%v; retrying (%d/%d)",
+			r.name, r.offset, err, attempt, r.retries)
+		time.Sleep(time.Duration(attempt) * r.delay)
+		if err = r.reopen(); err != nil {
+			continue
+		}
+		n, err = r.r.Read(p)
+		r.offset += int64(n)
+		if err == nil || err == io.EOF || n > 0 {
+			r.Recovered++
+			log.Printf("%s: recovered from read error at offset %d", r.name, r.offset-int64(n))
+			return n, err
+		}
+		if !isTransientReadError(err) {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+func (r *retryReader) reopen() error {
+	f, err := r.open(r.name)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(r.offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	r.r.Close()
+	r.r = f
+	return nil
+}
+
+// File returns the file currently being read, which differs from the one
+// passed to newRetryReader after a reopen.
+func (r *retryReader) File() (*os.File, bool) {
+	f, ok := r.r.(*os.File)
+	return f, ok
+}
+
+func (r *retryReader) Close() error {
+	return r.r.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "syscall"
+
+// transientReadErrors are the errors that network filesystems return for
+// reads that may succeed when retried; stale handles (ESTALE) are a unix
+// error, which plan9 does not define.
+var transientReadErrors = []error{syscall.EIO}
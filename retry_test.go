@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// flakyFile fails reads with err once it has handed out failAt bytes, until
+// it is reopened.
+type flakyFile struct {
+	*strings.Reader
+	failAt int64
+	err    error
+}
+
+func (f *flakyFile) Read(p []byte) (int, error) {
+	pos, _ := f.Seek(0, io.SeekCurrent)
+	if f.err != nil && pos >= f.failAt {
+		return 0, f.err
+	}
+	if f.err != nil && pos+int64(len(p)) > f.failAt {
+		p = p[:f.failAt-pos]
+	}
+	return f.Reader.Read(p)
+}
+
+func (f *flakyFile) Close() error { return nil }
+
+func TestRetryReader(t *testing.T) {
+	const content = "0123456789abcdefghij"
+	type test struct {
+		name      string
+		err       error
+		failOpens int
+		want      string
+		wantErr   bool
+	}
+	tests := []test{
+		{"eio after failed reopen", syscall.EIO, 1, content, false},
+		{"gives up", syscall.EIO, 5, content[:8], true},
+		{"not transient", errors.New("bad"), 0, content[:8], true},
+	}
+	// each of the errors of this platform
+	for _, err := range transientReadErrors {
+		tests = append(tests, test{err.Error(), err, 0, content, false})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opens := 0
+			r := &retryReader{
+				name: "test.log",
+				r:    &flakyFile{strings.NewReader(content), 8, tt.err},
+				open: func(string) (io.ReadSeekCloser, error) {
+					if opens++; opens <= tt.failOpens {
+						return nil, syscall.EIO
+					}
+					return &flakyFile{Reader: strings.NewReader(content)}, nil
+				},
+				retries: 3,
+			}
+			var got []byte
+			var err error
+			captureLog(t)
+			got, err = io.ReadAll(r)
+			if string(got) != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("read %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+			if !tt.wantErr && r.Recovered != 1 {
+				t.Errorf("Recovered = %d, want 1", r.Recovered)
+			}
+		})
+	}
+}
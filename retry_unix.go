@@ -0,0 +1,9 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// transientReadErrors are the errors that network filesystems return for
+// reads that may succeed when retried.
+var transientReadErrors = []error{syscall.EIO, syscall.ESTALE}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// awsCommand is the AWS CLI used to list and stream S3 objects, so that
+// its usual credential and region configuration applies.
+var awsCommand = "aws"
+
+func init() {
+	registerSource(&Source{Scheme: "s3", Expand: expandS3, Open: openS3})
+}
+
+// expandS3 lists the objects under an s3://bucket/prefix/ URI, or those
+// matching a glob in its last path element. Any other URI is one object.
+func expandS3(uri, include string) ([]string, error) {
+	dir, pattern, recursive := uri, "", false
+	switch {
+	case strings.ContainsAny(path.Base(uri), "*?["):
+		dir, pattern = uri[:strings.LastIndexByte(uri, '/')+1], path.Base(uri)
+	case strings.HasSuffix(uri, "/"):
+		pattern, recursive = include, true
+	default:
+		return []string{uri}, nil
+	}
+	out, err := commandOutput(awsCommand, "s3", "ls", "--recursive", dir)
+	if err != nil {
+		return nil, err
+	}
+	bucket := strings.SplitN(strings.TrimPrefix(uri, "s3://"), "/", 2)[0]
+	var objects []string
+	for _, line := range strings.Split(string(out), "\n") {
+		key, ok := s3ListKey(line)
+		if !ok {
+			continue
+		}
+		object := "s3://" + bucket + "/" + key
+		if !strings.HasPrefix(object, dir) || strings.HasSuffix(key, "/") {
+			continue
+		}
+		// a glob only matches directly below its prefix, like a local one
+		if !recursive && strings.Contains(strings.TrimPrefix(object, dir), "/") {
+			continue
+		}
+		if ok, _ := path.Match(pattern, path.Base(key)); ok {
+			objects = append(objects, object)
+		}
+	}
+	if len(objects) == 0 {
+		return nil, &ConfigError{"input", fmt.Errorf("no objects match %s", uri)}
+	}
+	return objects, nil
+}
+
+// s3ListKey returns the key of an "aws s3 ls" object line, the text after
+// its date, time and size, which may contain spaces:
+//
+//	2024-03-01 10:00:00      12345 logs/2024-03-01/app.log
+func s3ListKey(line string) (string, bool) {
+	rest := line
+	for i := 0; i < 3; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			return "", false
+		}
+		rest = rest[end:]
+	}
+	key := strings.TrimSpace(rest)
+	return key, key != ""
+}
+
+func openS3(uri string) (io.ReadCloser, error) {
+	return startCommand(awsCommand, "s3", "cp", "--quiet", uri, "-")
+}
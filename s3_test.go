@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// fakeCommand installs a shell script as the command stored in *name for
+// the duration of the test.
+func fakeCommand(t *testing.T, name *string, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "fake")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	saved := *name
+	*name = path
+	t.Cleanup(func() { *name = saved })
+}
+
+func TestS3Source(t *testing.T) {
+	fakeCommand(t, &awsCommand, `
+case "$2" in
+ls) cat <<'LIST'
+2024-03-01 10:00:00        413 logs/2024-03-01/app.log
+2024-03-01 10:00:00        120 logs/2024-03-01/app 2.log.gz
+2024-03-02 10:00:00        413 logs/app.log
+2024-03-02 10:00:00          9 logs/notes.txt
+2024-03-02 10:00:02          2 logs/tiny.log
+2024-03-10 10:10:00         10 logs/ten.log
+LIST
+;;
+cp) [ "$4" = s3://bucket/logs/app.log ] || { echo "no such key: $4" >&2; exit 1; }
+    cat testdata/access.log ;;
+esac
+`)
+	tests := []struct {
+		uri, include string
+		want         []string
+	}{
+		{"s3://bucket/logs/", "*.log*", []string{
+			"s3://bucket/logs/2024-03-01/app.log", "s3://bucket/logs/2024-03-01/app 2.log.gz", "s3://bucket/logs/app.log",
+			"s3://bucket/logs/tiny.log", "s3://bucket/logs/ten.log"}},
+		// sizes that also occur in the date or time
+		{"s3://bucket/logs/t*.log", "*", []string{"s3://bucket/logs/tiny.log", "s3://bucket/logs/ten.log"}},
+		{"s3://bucket/logs/*.txt", "*", []string{"s3://bucket/logs/notes.txt"}},
+		{"s3://bucket/logs/app.log", "*", []string{"s3://bucket/logs/app.log"}},
+	}
+	for _, tt := range tests {
+		got, err := expandInputs([]string{tt.uri}, tt.include)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandInputs(%s) = %q, %v; want %q", tt.uri, got, err, tt.want)
+		}
+	}
+	if _, err := expandInputs([]string{"s3://bucket/logs/*.csv"}, "*"); err == nil {
+		t.Error("glob without matching objects was accepted")
+	}
+
+	captureLog(t)
+	verbs, _ := parseVerbs("GET")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("s3://bucket/logs/app.log", verbs, TimeWindow{}, c)
+	if values := processLines(c, nil); len(values.Values) != 4 {
+		t.Errorf("read %d GET values from S3, want 4", len(values.Values))
+	}
+
+	r, err := openInput("s3://bucket/missing.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Read(make([]byte, 1))
+	if err := r.Close(); err == nil {
+		t.Error("failed download was not reported")
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// sampleRate is the share of lines read with -sample, and sampler decides
+// which; main sets both. Counts are scaled up by the inverse of the rate.
+var (
+	sampleRate = 1.0
+	sampler    *rand.Rand
+)
+
+// setSampling sets up -sample at rate with the given seed, so that the
+// same lines are sampled on every run.
+func setSampling(rate float64, seed int64) error {
+	if rate <= 0 || rate > 1 {
+		return &ConfigError{"sample", fmt.Errorf("rate %v is not in (0, 1]", rate)}
+	}
+	sampleRate = rate
+	if rate < 1 {
+		sampler = rand.New(rand.NewSource(seed))
+	}
+	return nil
+}
+
+// sampled reports whether the next line is part of the sample.
+func sampled() bool {
+	return sampler == nil || sampler.Float64() < sampleRate
+}
+
+// scaleCount estimates the number of values n sampled values stand for.
+func scaleCount(n int) int {
+	return int(math.Round(float64(n) / sampleRate))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	captureLog(t)
+	defer func() { sampleRate, sampler = 1, nil }()
+	var b strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&b, "GET /api %d\n", i%100)
+	}
+	path := filepath.Join(t.TempDir(), "big.log")
+	os.WriteFile(path, []byte(b.String()), 0644)
+	verbs, _ := parseVerbs("GET")
+	read := func() AggregatedValues {
+		c := make(chan LineMatch, ChanSize)
+		go filterValues(path, verbs, TimeWindow{}, c)
+		return processLines(c, nil)
+	}
+
+	if err := setSampling(0.1, 42); err != nil {
+		t.Fatal(err)
+	}
+	values := read()
+	if n := len(values.Values); n < 900 || n > 1100 {
+		t.Errorf("sampled %d of 10000 lines at 0.1", n)
+	}
+	if count := computePercentiles(values, []int{50}).Count; count < 9000 || count > 11000 {
+		t.Errorf("estimated count %d, want about 10000", count)
+	}
+
+	// every count of the report is an estimate for all lines
+	buf := captureLog(t)
+	fastThresholds = map[string]float32{"": 10}
+	defer func() { fastThresholds = nil }()
+	setSampling(0.1, 42)
+	values = read()
+	printTooFast(verbs.Verbs, verbs.Verbs, values)
+	fast, total := values.TooFast["GET"], values.Counts["GET"]
+	if want := fmt.Sprintf("GET: %d of %d values", scaleCount(fast), scaleCount(total)); !strings.Contains(buf.String(), want) {
+		t.Errorf("printTooFast logged %q, want %q", buf, want)
+	}
+	if cdfs := computeCDFs(map[string]*AggregatedValues{"GET": &values}, 0, nil); cdfs[0].Count != scaleCount(len(values.Values)) {
+		t.Errorf("CDF count %d of %d sampled values", cdfs[0].Count, len(values.Values))
+	}
+	fastThresholds = nil
+
+	setSampling(0.1, 42)
+	if again := read(); len(again.Values) != len(values.Values) {
+		t.Errorf("same seed sampled %d lines, then %d", len(values.Values), len(again.Values))
+	}
+	for _, rate := range []float64{0, -1, 1.5} {
+		if err := setSampling(rate, 1); err == nil {
+			t.Errorf("rate %v was accepted", rate)
+		}
+	}
+}
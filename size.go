@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = []struct {
+	Suffix string
+	Bytes  int64
+}{
+	{"G", 1 << 30}, {"M", 1 << 20}, {"k", 1 << 10}, {"K", 1 << 10},
+}
+
+// parseSize parses a byte count with an optional k, M or G (binary) suffix.
+func parseSize(s string) (int64, error) {
+	mult := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.Suffix) {
+			s, mult = strings.TrimSuffix(s, u.Suffix), u.Bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+func formatSize(n int64) string {
+	for _, u := range sizeUnits {
+		if n >= u.Bytes && n%u.Bytes == 0 {
+			return strconv.FormatInt(n/u.Bytes, 10) + u.Suffix
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func parseSizeBuckets(s string) ([]int64, error) {
+	var bounds []int64
+	for _, part := range strings.Split(s, ",") {
+		n, err := parseSize(strings.TrimSpace(part))
+		if err != nil {
+			return nil, &ConfigError{"size-buckets", err}
+		}
+		bounds = append(bounds, n)
+	}
+	if !sort.SliceIsSorted(bounds, func(i, j int) bool { return bounds[i] < bounds[j] }) {
+		return nil, &ConfigError{"size-buckets", errors.New("boundaries must be increasing")}
+	}
+	return bounds, nil
+}
+
+// groupBySize counts each line under the size bucket of its field'th
+// whitespace-separated field. A "-" size, as logged for empty responses, is
+// zero; lines without a readable size are counted under "unknown".
+func groupBySize(field int, bounds []int64) *Grouping {
+	names := make([]string, len(bounds)+1)
+	names[0] = "<" + formatSize(bounds[0])
+	for i := 1; i < len(bounds); i++ {
+		names[i] = formatSize(bounds[i-1]) + "-" + formatSize(bounds[i])
+	}
+	names[len(bounds)] = ">=" + formatSize(bounds[len(bounds)-1])
+
+	rank := make(map[string]int, len(names))
+	for i, name := range names {
+		rank[name] = i
+	}
+	rank["unknown"] = len(names)
+
+	return &Grouping{
+		Label: "size",
+		Keys: func(dst []string, m LineMatch) []string {
+			s, ok := nthField(m.Line, field)
+			if !ok {
+				return append(dst, "unknown")
+			}
+			var size int64
+			if s != "-" {
+				var err error
+				if size, err = parseSize(s); err != nil {
+					return append(dst, "unknown")
+				}
+			}
+			return append(dst, names[sort.Search(len(bounds), func(b int) bool { return bounds[b] > size })])
+		},
+		NameLess: func(a, b string) bool { return rank[a] < rank[b] },
+	}
+}
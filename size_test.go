@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSizeBuckets(t *testing.T) {
+	got, err := parseSizeBuckets("512, 1k,1.5M,2G")
+	want := []int64{512, 1024, 1536 * 1024, 2 << 30}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSizeBuckets = %v, %v, want %v", got, err, want)
+	}
+	for _, bad := range []string{"", "1k,x", "10k,1k", "-1"} {
+		var cerr *ConfigError
+		if _, err := parseSizeBuckets(bad); !errors.As(err, &cerr) {
+			t.Errorf("parseSizeBuckets(%q) err = %v, want ConfigError", bad, err)
+		}
+	}
+}
+
+func TestGroupBySize(t *testing.T) {
+	g := groupBySize(-2, []int64{1024, 10240})
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"GET /x 100 12.5", "<1k"},
+		{"GET /x - 12.5", "<1k"},
+		{"GET /x 1024 12.5", "1k-10k"},
+		{"GET /x 10k 12.5", ">=10k"},
+		{"GET /x big 12.5", "unknown"},
+		{"12.5", "unknown"},
+	}
+	for _, tt := range tests {
+		got := g.Keys(nil, LineMatch{Line: tt.line})
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("size bucket of %q = %v, want %s", tt.line, got, tt.want)
+		}
+	}
+
+	groups := []GroupPercentiles{{Name: "unknown"}, {Name: ">=10k"}, {Name: "<1k"}, {Name: "1k-10k"}}
+	sortGroups(groups, SortKey{Field: "name"}, false, g.NameLess)
+	var names []string
+	for _, r := range groups {
+		names = append(names, r.Name)
+	}
+	if want := []string{"<1k", "1k-10k", ">=10k", "unknown"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("size rows sorted = %v, want %v", names, want)
+	}
+}
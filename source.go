@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Source reads inputs named by a URI scheme, such as s3://, usually by
+// running the command line tool of the service.
+type Source struct {
+	Scheme string
+	// Expand lists the inputs uri stands for, e.g. the objects under an
+	// S3 prefix, keeping those whose base name matches include. nil means
+	// every URI is a single input.
+	Expand func(uri, include string) ([]string, error)
+	Open   func(uri string) (io.ReadCloser, error)
+	// Follows is set for sources that Open as an endless stream with
+	// -follow.
+	Follows bool
+}
+
+var sources = make(map[string]*Source)
+
+func registerSource(src *Source) {
+	sources[src.Scheme] = src
+	features = append(features, "input:"+src.Scheme)
+}
+
+// sourceFor returns the Source for the scheme of name, or nil if name is
+// not a URI of a registered scheme.
+func sourceFor(name string) *Source {
+	scheme, _, ok := strings.Cut(name, "://")
+	if !ok {
+		return nil
+	}
+	return sources[scheme]
+}
+
+// followable reports whether -follow can read name: a local file, or the URI
+// of a Source that Follows.
+func followable(name string) bool {
+	src := sourceFor(name)
+	return src == nil || src.Follows
+}
+
+// isStream reports whether name is read front to back only: stdin, a Source
+// URI or a named pipe or socket, as opposed to a local file that can be
+// seeked and reopened.
+func isStream(name string) bool {
+	return name == "-" || sourceFor(name) != nil || *follow || isPipe(name)
+}
+
+// isInputArg reports whether the argument name names an input rather than
+// verbs: stdin, a URI, an existing file or pipe, or a glob. Unlike isStream
+// it doesn't depend on -follow.
+func isInputArg(name string) bool {
+	if _, err := os.Stat(name); err == nil {
+		return true
+	}
+	return name == "-" || sourceFor(name) != nil || strings.ContainsAny(name, "*?[")
+}
+
+// openInput opens filename for reading: stdin for "-", a Source for URIs,
+// named pipes and sockets until their writer is done, and the local file
+// otherwise, followed with -follow.
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename == "-" {
+		return os.Stdin, nil
+	}
+	if src := sourceFor(filename); src != nil {
+		return src.Open(filename)
+	}
+	if isPipe(filename) {
+		return openPipe(filename)
+	}
+	if *follow {
+		return openFollow(filename)
+	}
+	return os.Open(filename)
+}
+
+// commandReader streams the standard output of a command. Close waits for
+// the command and reports its failure along with the end of its stderr.
+type commandReader struct {
+	stdout io.ReadCloser
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	eof    bool
+}
+
+func startCommand(name string, args ...string) (io.ReadCloser, error) {
+	return startFilter(nil, name, args...)
+}
+
+// startFilter is startCommand with stdin as the standard input.
+func startFilter(stdin io.Reader, name string, args ...string) (*commandReader, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = stdin
+	r := &commandReader{cmd: cmd}
+	cmd.Stderr = &r.stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	r.stdout = stdout
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// startMerged is startCommand with the command's stderr interleaved into
+// its output, for commands like docker logs that relay both streams of a
+// program. A failure is reported without its message, which was output.
+func startMerged(name string, args ...string) (io.ReadCloser, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdout, cmd.Stderr = pw, pw
+	err = cmd.Start()
+	// the command has its own copy
+	pw.Close()
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	return &commandReader{stdout: pr, cmd: cmd}, nil
+}
+
+func (r *commandReader) Read(p []byte) (int, error) {
+	n, err := r.stdout.Read(p)
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+func (r *commandReader) Close() error {
+	if !r.eof {
+		// stopped early, e.g. by -until: don't download the rest
+		r.cmd.Process.Kill()
+		r.cmd.Wait()
+		return nil
+	}
+	if err := r.cmd.Wait(); err != nil {
+		msg := strings.TrimSpace(r.stderr.String())
+		if i := strings.LastIndexByte(msg, '\n'); i >= 0 {
+			msg = msg[i+1:]
+		}
+		return fmt.Errorf("%s: %v: %s", strings.Join(r.cmd.Args, " "), err, msg)
+	}
+	return nil
+}
+
+// commandOutput runs a command and returns its standard output, with the
+// end of its stderr in the error if it fails.
+func commandOutput(name string, args ...string) ([]byte, error) {
+	r, err := startCommand(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := io.ReadAll(r)
+	if cerr := r.Close(); cerr != nil {
+		return nil, cerr
+	}
+	return out, err
+}
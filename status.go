@@ -0,0 +1,20 @@
+package main
+
+// statusClass returns the class of the -status-key field of line, such as
+// "2xx", or "other" if it isn't a three-digit status code.
+func statusClass(line string, obj jsonObject) string {
+	s, _ := lineField(line, obj, *statusKey)
+	if len(s) != 3 || s[0] < '1' || s[0] > '5' || s[1] < '0' || s[1] > '9' || s[2] < '0' || s[2] > '9' {
+		return "other"
+	}
+	return s[:1] + "xx"
+}
+
+// groupByStatusClass counts each line under its status class. Class names
+// sort in their natural order, with "other" last.
+var groupByStatusClass = &Grouping{
+	Label: "status",
+	Keys: func(dst []string, m LineMatch) []string {
+		return append(dst, statusClass(m.Line, m.Object))
+	},
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestStatusClasses(t *testing.T) {
+	captureLog(t)
+	defer func(key string) { *statusKey = key }(*statusKey)
+	*statusKey = "5"
+	for line, want := range map[string]string{
+		"2024-03-01T10:00:00Z host1 GET /api 200 12.5": "2xx",
+		"2024-03-01T10:00:00Z host1 GET /api 503 12.5": "5xx",
+		"2024-03-01T10:00:00Z host1 GET /api 20 12.5":  "other",
+		"2024-03-01T10:00:00Z host1 GET /api 600 12.5": "other",
+		"short line": "other",
+	} {
+		if got := statusClass(line, nil); got != want {
+			t.Errorf("statusClass(%q) = %q, want %q", line, got, want)
+		}
+	}
+
+	verbs, _ := parseVerbs("GET,POST,DELETE")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("testdata/access.log", verbs, TimeWindow{}, c)
+	values := processLines(c, nil)
+	total := 0
+	for _, class := range values.Classes {
+		total += len(class.Values)
+	}
+	if total != len(values.Values) || values.Classes["2xx"] == nil {
+		t.Errorf("status classes %v don't add up to the %d values", values.Classes, len(values.Values))
+	}
+
+	*splitStatus = false
+	defer func() { *splitStatus = true }()
+	c = make(chan LineMatch, ChanSize)
+	go filterValues("testdata/access.log", verbs, TimeWindow{}, c)
+	if values := processLines(c, nil); values.Classes != nil {
+		t.Error("status classes collected with -split-status=false")
+	}
+}
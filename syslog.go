@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func init() {
+	registerSource(&Source{Scheme: "syslog", Open: openSyslog})
+}
+
+// openSyslog listens for syslog messages on the UDP and TCP address of a
+// syslog://addr URI and returns them as lines until the process is
+// interrupted, which ends the input and lets the report be printed.
+func openSyslog(uri string) (io.ReadCloser, error) {
+	l, err := listenSyslog(strings.TrimPrefix(uri, "syslog://"))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("listening for syslog on %s (udp and tcp), interrupt to report", l.Addr())
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		signal.Stop(stop)
+		l.Close()
+	}()
+	return l, nil
+}
+
+// syslogListener accepts RFC 3164 and RFC 5424 messages over UDP, and over
+// TCP either newline delimited or octet counted (RFC 6587). Each message is
+// read as one line: "<timestamp> <host> <app>: <message>".
+type syslogListener struct {
+	udp net.PacketConn
+	tcp net.Listener
+	r   *io.PipeReader
+	w   *io.PipeWriter
+
+	mu     sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func listenSyslog(addr string) (*syslogListener, error) {
+	tcp, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	// the same port for UDP, also when addr asked for any free one
+	udp, err := net.ListenPacket("udp", tcp.Addr().String())
+	if err != nil {
+		tcp.Close()
+		return nil, err
+	}
+	l := &syslogListener{udp: udp, tcp: tcp, conns: make(map[net.Conn]bool)}
+	l.r, l.w = io.Pipe()
+	l.wg.Add(2)
+	go l.serveUDP()
+	go l.serveTCP()
+	return l, nil
+}
+
+func (l *syslogListener) Addr() net.Addr { return l.tcp.Addr() }
+
+func (l *syslogListener) Read(p []byte) (int, error) { return l.r.Read(p) }
+
+// Close stops listening, ends the connections and, once the messages
+// received so far are written, the input.
+func (l *syslogListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.udp.Close()
+	l.tcp.Close()
+	for c := range l.conns {
+		c.Close()
+	}
+	l.mu.Unlock()
+	go func() {
+		l.wg.Wait()
+		l.w.Close()
+	}()
+	return nil
+}
+
+func (l *syslogListener) write(msg []byte) {
+	line := parseSyslog(msg, time.Now())
+	// writes to the pipe are atomic, so lines from connections don't mix
+	l.w.Write([]byte(line + "\n"))
+}
+
+func (l *syslogListener) serveUDP() {
+	defer l.wg.Done()
+	buf := make([]byte, 64<<10)
+	for {
+		n, _, err := l.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		l.write(buf[:n])
+	}
+}
+
+func (l *syslogListener) serveTCP() {
+	defer l.wg.Done()
+	for {
+		c, err := l.tcp.Accept()
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			c.Close()
+			return
+		}
+		l.conns[c] = true
+		l.wg.Add(1)
+		l.mu.Unlock()
+		go l.serveConn(c)
+	}
+}
+
+func (l *syslogListener) serveConn(c net.Conn) {
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, c)
+		l.mu.Unlock()
+		c.Close()
+		l.wg.Done()
+	}()
+	r := bufio.NewReaderSize(c, BuffSize)
+	for {
+		first, err := r.Peek(1)
+		if err != nil {
+			return
+		}
+		var msg []byte
+		if first[0] >= '0' && first[0] <= '9' {
+			// octet counting: "<length> <message>"
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(length))
+			if err != nil || n > BuffSize {
+				log.Printf("syslog: bad frame length %q from %s", length, c.RemoteAddr())
+				return
+			}
+			msg = make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+		} else if msg, err = r.ReadBytes('\n'); err != nil && len(msg) == 0 {
+			return
+		}
+		l.write(msg)
+	}
+}
+
+// parseSyslog turns an RFC 3164 or RFC 5424 message into a line starting
+// with an RFC 3339 timestamp, the host and the app. RFC 3164 timestamps
+// have neither year nor zone, so those messages are stamped with
+// received instead.
+func parseSyslog(msg []byte, received time.Time) string {
+	s := string(bytes.TrimRight(msg, "\r\n\x00"))
+	if strings.HasPrefix(s, "<") {
+		if end := strings.IndexByte(s, '>'); end > 0 && end <= 4 {
+			s = s[end+1:]
+		}
+	}
+	stamp := received.UTC().Format(time.RFC3339Nano)
+
+	if rest, ok := strings.CutPrefix(s, "1 "); ok {
+		// RFC 5424: TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+		fields := strings.SplitN(rest, " ", 6)
+		if len(fields) == 6 {
+			if ts, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+				stamp = ts.UTC().Format(time.RFC3339Nano)
+			}
+			text := skipStructuredData(fields[5])
+			text = strings.TrimPrefix(text, "\ufeff")
+			return stamp + " " + fields[1] + " " + fields[2] + ": " + text
+		}
+	}
+	// RFC 3164: "Mmm dd hh:mm:ss HOSTNAME TAG: MSG"
+	if len(s) > len(time.Stamp) {
+		if _, err := time.Parse(time.Stamp, s[:len(time.Stamp)]); err == nil {
+			s = strings.TrimLeft(s[len(time.Stamp):], " ")
+		}
+	}
+	return stamp + " " + s
+}
+
+// skipStructuredData returns what follows the STRUCTURED-DATA at the start
+// of s, which is either "-" or a run of [...] elements whose quoted values
+// may contain escaped \] and \".
+func skipStructuredData(s string) string {
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		return strings.TrimPrefix(rest, " ")
+	}
+	inElement, inQuote := false, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && inQuote:
+			i++
+		case c == '"' && inElement:
+			inQuote = !inQuote
+		case c == '[' && !inQuote:
+			inElement = true
+		case c == ']' && !inQuote:
+			inElement = false
+		case !inElement:
+			return strings.TrimPrefix(s[i:], " ")
+		}
+	}
+	return ""
+}
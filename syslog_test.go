@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseSyslog(t *testing.T) {
+	received := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct{ msg, want string }{
+		{"<34>1 2024-03-01T09:59:58.5+01:00 web1 nginx 123 - - GET /a 200 12.5\n",
+			"2024-03-01T08:59:58.5Z web1 nginx: GET /a 200 12.5"},
+		{`<165>1 2024-03-01T09:00:00Z web1 nginx - ID47 [ex@1 a="x\]y" b="[z]"][ex@2 c="d"] GET /b 200 3`,
+			"2024-03-01T09:00:00Z web1 nginx: GET /b 200 3"},
+		{"<13>1 - web1 app - - - \ufeffPOST /c 201 40",
+			"2024-03-01T10:00:00Z web1 app: POST /c 201 40"},
+		{"<34>Mar  1 09:59:58 web2 nginx[42]: GET /d 200 7",
+			"2024-03-01T10:00:00Z web2 nginx[42]: GET /d 200 7"},
+		{"GET /e 200 1", "2024-03-01T10:00:00Z GET /e 200 1"},
+	}
+	for _, tt := range tests {
+		if got := parseSyslog([]byte(tt.msg), received); got != tt.want {
+			t.Errorf("parseSyslog(%q)\n got %q\nwant %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestSyslogListener(t *testing.T) {
+	l, err := listenSyslog("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp, err := net.Dial("udp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	tcp, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+
+	lines := bufio.NewScanner(l)
+	next := func() string {
+		if !lines.Scan() {
+			t.Fatalf("input ended early: %v", lines.Err())
+		}
+		return lines.Text()
+	}
+	fmt.Fprint(udp, "<34>1 2024-03-01T10:00:00Z web1 nginx - - - GET /a 200 1")
+	if got := next(); got != "2024-03-01T10:00:00Z web1 nginx: GET /a 200 1" {
+		t.Errorf("udp message read as %q", got)
+	}
+	msg := "<34>1 2024-03-01T10:00:01Z web1 nginx - - - GET /b 200 2"
+	fmt.Fprintf(tcp, "%s\n%d %s", msg, len(msg), msg)
+	for i := 0; i < 2; i++ {
+		if got := next(); got != "2024-03-01T10:00:01Z web1 nginx: GET /b 200 2" {
+			t.Errorf("tcp message %d read as %q", i, got)
+		}
+	}
+
+	l.Close()
+	if lines.Scan() {
+		t.Errorf("read %q after Close", lines.Text())
+	}
+}
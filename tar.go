@@ -0,0 +1,139 @@
+package main
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+func init() {
+	registerSource(&Source{Scheme: "tar", Expand: expandTar, Open: openTar})
+}
+
+// isArchive reports whether the local file name is a tar archive, plain
+// or compressed, by its extension.
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".tar", ".tgz", ".tar.gz", ".tar.bz2", ".tar.zst", ".tar.lz4"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTarURI splits tar://<archive>#<member> into the archive's file name
+// and the member's, which is "" for all of them.
+func splitTarURI(uri string) (archive, member string) {
+	archive, member, _ = strings.Cut(strings.TrimPrefix(uri, "tar://"), "#")
+	return archive, member
+}
+
+// openArchive opens the tar archive at name, decompressing it if needed.
+// Closing the closer ends the decompression.
+func openArchive(name string) (*tar.Reader, io.Closer, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := decompress(sniffCompression(f))
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return tar.NewReader(r), archiveCloser{f, r}, nil
+}
+
+type archiveCloser struct {
+	f *os.File
+	r io.Reader
+}
+
+func (c archiveCloser) Close() error {
+	if rc, ok := c.r.(io.Closer); ok {
+		rc.Close()
+	}
+	return c.f.Close()
+}
+
+// expandTar lists the regular files in a tar://<archive> whose base name
+// matches include, as tar://<archive>#<member> inputs in archive order. A
+// URI naming a member is that member.
+func expandTar(uri, include string) ([]string, error) {
+	archive, member := splitTarURI(uri)
+	if member != "" {
+		return []string{uri}, nil
+	}
+	tr, closer, err := openArchive(archive)
+	if err != nil {
+		return nil, &ConfigError{"input", err}
+	}
+	defer closer.Close()
+	var members []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &ConfigError{"input", fmt.Errorf("%s: %v", archive, err)}
+		}
+		if ok, _ := path.Match(include, path.Base(hdr.Name)); ok && hdr.Typeflag == tar.TypeReg {
+			members = append(members, "tar://"+archive+"#"+hdr.Name)
+		}
+	}
+	if len(members) == 0 {
+		return nil, &ConfigError{"input", fmt.Errorf("no members of %s match -include %q", archive, include)}
+	}
+	return members, nil
+}
+
+// tarCursor is the archive read last, left after the member opened last,
+// so that opening its members in order reads the archive only once.
+var tarCursor struct {
+	archive string
+	tr      *tar.Reader
+	closer  io.Closer
+}
+
+// openTar returns a reader of the member named by a
+// tar://<archive>#<member> URI.
+func openTar(uri string) (io.ReadCloser, error) {
+	archive, member := splitTarURI(uri)
+	if member == "" {
+		return nil, &ConfigError{"input", fmt.Errorf("%s: no member, want tar://<archive>#<member>", uri)}
+	}
+	// the member may be ahead of the cursor, or if not, from the start
+	for fromStart := tarCursor.archive != archive; ; fromStart = true {
+		if fromStart {
+			if tarCursor.closer != nil {
+				tarCursor.closer.Close()
+				tarCursor.archive, tarCursor.closer = "", nil
+			}
+			tr, closer, err := openArchive(archive)
+			if err != nil {
+				return nil, err
+			}
+			tarCursor.archive, tarCursor.tr, tarCursor.closer = archive, tr, closer
+		}
+		for {
+			hdr, err := tarCursor.tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", archive, err)
+			}
+			if hdr.Name == member {
+				// the cursor stays open for the next member
+				return io.NopCloser(tarCursor.tr), nil
+			}
+		}
+		if fromStart {
+			return nil, fmt.Errorf("%s: no member %s", archive, member)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestArchive(t *testing.T, path string, files map[string]string, order []string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	tw.WriteHeader(&tar.Header{Name: "app/", Typeflag: tar.TypeDir, Mode: 0755})
+	for _, name := range order {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name]))})
+		io.WriteString(tw, files[name])
+	}
+	tw.Close()
+	zw.Close()
+	f.Close()
+}
+
+func TestTarInputs(t *testing.T) {
+	captureLog(t)
+	path := filepath.Join(t.TempDir(), "day.tar.gz")
+	writeTestArchive(t, path, map[string]string{
+		"app/a.log":      "GET /a 1\nGET /a 2\n",
+		"app/b.log":      "GET /b 3\nPOST /b 4",
+		"app/readme.txt": "GET /x 1000\n",
+	}, []string{"app/a.log", "app/b.log", "app/readme.txt"})
+
+	got, err := expandInputs([]string{path}, "*.log")
+	want := []string{"tar://" + path + "#app/a.log", "tar://" + path + "#app/b.log"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("expandInputs = %q, %v; want %q", got, err, want)
+	}
+	if _, err := expandInputs([]string{path}, "*.csv"); err == nil {
+		t.Error("an archive without matching members was accepted")
+	}
+
+	verbs, _ := parseVerbs("GET")
+	read := func(inputs ...string) AggregatedValues {
+		c := make(chan LineMatch, ChanSize)
+		go filterFiles(inputs, verbs, TimeWindow{}, c)
+		return processLines(c, groupByFile)
+	}
+	values := read(got...)
+	if len(values.Values) != 3 || len(values.Groups[want[1]].Values) != 1 {
+		t.Errorf("read %v from the archive", values.Values)
+	}
+	// members out of order reopen the archive
+	if values := read(want[1], want[0]); len(values.Values) != 3 {
+		t.Errorf("read %v from members out of order", values.Values)
+	}
+
+	r, err := openInput("tar://" + path + "#app/missing.log")
+	if err == nil {
+		r.Close()
+		t.Error("a missing member was opened")
+	}
+}
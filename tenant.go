@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// tenantOf returns the -tenant-key field of line, or "-" if it has none.
+func tenantOf(line string, obj jsonObject) string {
+	v, ok := lineField(line, obj, *tenantKey)
+	if !ok || v == "" {
+		return "-"
+	}
+	return v
+}
+
+// TenantRanking is one of the top lists of the -tenant-key report.
+type TenantRanking struct {
+	By      string // "requests", "total value" or "P99"
+	Tenants []GroupPercentiles
+}
+
+// rankTenants returns the top n tenants by request count, by total value,
+// the load they put on the backends, and by P99.
+func rankTenants(tenants []GroupPercentiles, n int) []TenantRanking {
+	columns := []struct {
+		by  string
+		key func(g GroupPercentiles) float32
+	}{
+		{"requests", func(g GroupPercentiles) float32 { return float32(g.Count) }},
+		{"total value", func(g GroupPercentiles) float32 { return g.Average * float32(g.Count) }},
+		{"P99", func(g GroupPercentiles) float32 { return g.Percentiles[99] }},
+	}
+	var rankings []TenantRanking
+	for _, c := range columns {
+		top := append([]GroupPercentiles(nil), tenants...)
+		sort.Slice(top, func(i, j int) bool {
+			if a, b := c.key(top[i]), c.key(top[j]); a != b {
+				return a > b
+			}
+			return top[i].Name < top[j].Name
+		})
+		if n > 0 && len(top) > n {
+			top = top[:n]
+		}
+		rankings = append(rankings, TenantRanking{c.by, top})
+	}
+	return rankings
+}
+
+// tenantRows formats the rows of a ranking, with the shares of summary's
+// requests and total.
+func tenantRows(r TenantRanking, summary PercentileValues) [][]string {
+	total := summary.Average * float32(summary.Count)
+	var rows [][]string
+	for _, g := range r.Tenants {
+		sum := g.Average * float32(g.Count)
+		rows = append(rows, []string{g.Name, strconv.Itoa(g.Count),
+			formatShare(float32(g.Count) / float32(summary.Count)),
+			fmt.Sprintf("%.3f", sum), formatShare(sum / total),
+			fmt.Sprintf("%.3f", g.Average), fmt.Sprintf("%.3f", g.Percentiles[99])})
+	}
+	return rows
+}
+
+var tenantHeader = []string{"tenant", "count", "share", "total", "share", "avg", "P99%"}
+
+func printTenants(rankings []TenantRanking, summary PercentileValues) {
+	var b strings.Builder
+	for _, r := range rankings {
+		fmt.Fprintf(&b, "\ntop %d tenants by %s:\n", len(r.Tenants), r.By)
+		tw := tabwriter.NewWriter(&b, 0, 0, 4, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(tenantHeader, "\t"))
+		for _, row := range tenantRows(r, summary) {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		tw.Flush()
+	}
+	log.Print(b.String())
+}
+
+func writeMarkdownTenants(w io.Writer, rankings []TenantRanking, summary PercentileValues) {
+	for _, r := range rankings {
+		fmt.Fprintf(w, "\nTop %d tenants by %s:\n\n| %s |\n|---|---:|---:|---:|---:|---:|---:|\n",
+			len(r.Tenants), r.By, strings.Join(tenantHeader, " | "))
+		for _, row := range tenantRows(r, summary) {
+			row[0] = strings.ReplaceAll(row[0], "|", `\|`)
+			fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+		}
+	}
+}
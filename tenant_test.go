@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRankTenants(t *testing.T) {
+	captureLog(t)
+	defer func(key string) { *tenantKey = key }(*tenantKey)
+	*tenantKey = "2"
+	verbs, _ := parseVerbs("GET,POST,DELETE")
+	c := make(chan LineMatch, ChanSize)
+	go filterValues("testdata/access.log", verbs, TimeWindow{}, c)
+	values := processLines(c, nil)
+	rows := computeGroupPercentiles(AggregatedValues{Groups: values.Tenants}, PERCENTILES[:])
+
+	rankings := rankTenants(rows, 1)
+	if len(rankings) != 3 {
+		t.Fatalf("got %d rankings, want 3", len(rankings))
+	}
+	for _, r := range rankings {
+		if len(r.Tenants) != 1 || r.Tenants[0].Name != "host2" {
+			t.Errorf("top tenant by %s = %v, want host2", r.By, r.Tenants)
+		}
+	}
+
+	var buf bytes.Buffer
+	writeMarkdownTenants(&buf, rankings[:1], computePercentiles(values, PERCENTILES[:]))
+	if !strings.Contains(buf.String(), "| host2 | 4 | 57.14% | 134.250 | 64.23% | 33.562 | 95.750 |") {
+		t.Errorf("markdown:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// timestampLayouts are tried in order, against spans of one to three
+// whitespace-separated fields joined by single spaces. Layouts that extend
+// another one with a following field come first.
+var timestampLayouts = []struct {
+	Span   int
+	Layout string
+}{
+	{1, time.RFC3339Nano},
+	{1, "2006-01-02T15:04:05.999999999"},
+	{2, "02/Jan/2006:15:04:05 -0700"},
+	{1, "02/Jan/2006:15:04:05"},
+	{2, "2006-01-02 15:04:05.999999999Z07:00"},
+	{2, "2006-01-02 15:04:05.999999999"},
+	{2, "2006-01-02 15:04:05,999"},
+	{2, "2006/01/02 15:04:05.999999"},
+	{3, "Jan 2 15:04:05"},
+}
+
+// TimestampFormat locates and parses the timestamp of a line: Span fields
+// starting at the 0-based field index Field, in Layout.
+type TimestampFormat struct {
+	Field  int
+	Span   int
+	Layout string
+}
+
+// detectTimestampFormat returns the format of the first timestamp found in
+// line, looking at the leading fields only.
+func detectTimestampFormat(line string) (TimestampFormat, bool) {
+	fields := strings.Fields(line)
+	for i := 0; i < len(fields) && i < 8; i++ {
+		for _, l := range timestampLayouts {
+			if i+l.Span > len(fields) {
+				continue
+			}
+			if _, err := time.Parse(l.Layout, joinTimestamp(fields[i:i+l.Span])); err == nil {
+				return TimestampFormat{i, l.Span, l.Layout}, true
+			}
+		}
+	}
+	return TimestampFormat{}, false
+}
+
+// Parse extracts the timestamp of line, which must have the same layout as
+// the line the format was detected on.
+func (f TimestampFormat) Parse(line string) (time.Time, bool) {
+	fields := strings.Fields(line)
+	if f.Field+f.Span > len(fields) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(f.Layout, joinTimestamp(fields[f.Field:f.Field+f.Span]))
+	if err == nil && t.Year() == 0 {
+		t = assumeYear(t, time.Now())
+	}
+	return t, err == nil
+}
+
+// assumeYear gives t, parsed from a layout without a year such as syslog's,
+// the year that puts it within the year before now. A day of slack allows
+// for stamps in time zones ahead of UTC.
+func assumeYear(t, now time.Time) time.Time {
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+// joinTimestamp joins fields with a space, dropping the brackets access logs
+// put around timestamps.
+func joinTimestamp(fields []string) string {
+	s := strings.Join(fields, " ")
+	return strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+}
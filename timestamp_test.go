@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectTimestampFormat(t *testing.T) {
+	tests := []struct {
+		line  string
+		field int
+		want  time.Time
+	}{
+		{"2024-03-01T10:00:00Z host GET /x 12",
+			0, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{"2024-03-01T10:00:00.250+02:00 GET 12",
+			0, time.Date(2024, 3, 1, 8, 0, 0, 250e6, time.UTC)},
+		{`10.0.0.1 - - [01/Mar/2024:10:00:00 +0000] "GET /x HTTP/1.1" 200 12`,
+			3, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{"2024-03-01 10:00:00,123 INFO GET 12",
+			0, time.Date(2024, 3, 1, 10, 0, 0, 123e6, time.UTC)},
+		{"2024/03/01 10:00:00 GET 12",
+			0, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		// without a year, the last March 1 up to now
+		{"Mar  1 10:00:00 host app: GET 12",
+			0, assumeYear(time.Date(0, 3, 1, 10, 0, 0, 0, time.UTC), time.Now())},
+	}
+	for _, tt := range tests {
+		format, ok := detectTimestampFormat(tt.line)
+		if !ok || format.Field != tt.field {
+			t.Errorf("detectTimestampFormat(%q) = %+v, %v, want field %d", tt.line, format, ok, tt.field)
+			continue
+		}
+		got, ok := format.Parse(tt.line)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %v, %v, want %v", tt.line, got, ok, tt.want)
+		}
+	}
+
+	if format, ok := detectTimestampFormat("GET /x 200 12.5"); ok {
+		t.Errorf("detected %+v in a line without timestamp", format)
+	}
+}
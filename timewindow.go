@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// TimeWindow restricts processing to lines whose timestamp is in
+// [Since, Until). A zero bound is open.
+type TimeWindow struct {
+	Since time.Time
+	Until time.Time
+	// Sorted asserts the input is in timestamp order, allowing a binary
+	// search for Since and stopping at the first line past Until.
+	Sorted bool
+}
+
+func (w TimeWindow) Active() bool {
+	return !w.Since.IsZero() || !w.Until.IsZero()
+}
+
+var timeArgLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseTimeArg parses an absolute time, taken as UTC when it has no zone,
+// or a duration meaning that long before now.
+func parseTimeArg(option, s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeArgLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, &ConfigError{option, fmt.Errorf("%q is neither a time like 2006-01-02T15:04:05Z nor a duration like 2h", s)}
+}
+
+const seekBlockSize = 64 * 1024
+
+// seekToTime positions f at the start of a line at or shortly before the
+// first line stamped at or after since, assuming lines are in timestamp
+// order. It returns the offset it seeked to.
+func seekToTime(f *os.File, since time.Time) (int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	format, ok, err := firstTimestampFormat(f)
+	if err != nil || !ok {
+		f.Seek(0, io.SeekStart)
+		return 0, err
+	}
+
+	lo, hi := int64(0), fi.Size()
+	for hi-lo > seekBlockSize {
+		mid := lo + (hi-lo)/2
+		start, ts, ok, err := timestampAfter(f, mid, hi, format)
+		if err != nil {
+			return 0, err
+		}
+		if ok && ts.Before(since) {
+			lo = start
+		} else {
+			hi = mid
+		}
+	}
+	_, err = f.Seek(lo, io.SeekStart)
+	return lo, err
+}
+
+func firstTimestampFormat(f *os.File) (TimestampFormat, bool, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return TimestampFormat{}, false, err
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), BuffSize)
+	for i := 0; i < 100 && scanner.Scan(); i++ {
+		if format, ok := detectTimestampFormat(scanner.Text()); ok {
+			return format, true, nil
+		}
+	}
+	return TimestampFormat{}, false, scanner.Err()
+}
+
+// timestampAfter finds the first line starting after offset (or at it, for
+// offset 0) and before limit that has a timestamp, and returns its start.
+func timestampAfter(f *os.File, offset, limit int64, format TimestampFormat) (int64, time.Time, bool, error) {
+	r := bufio.NewReaderSize(io.NewSectionReader(f, offset, limit-offset), seekBlockSize)
+	pos := offset
+	if offset > 0 {
+		skipped, err := r.ReadSlice('\n')
+		pos += int64(len(skipped))
+		if err != nil {
+			return 0, time.Time{}, false, nil
+		}
+	}
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 && err == nil {
+			if ts, ok := format.Parse(string(bytes.TrimRight(line, "\r\n"))); ok {
+				return pos, ts, true, nil
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return 0, time.Time{}, false, err
+		}
+		pos += int64(len(line))
+	}
+}
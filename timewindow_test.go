@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var windowBase = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// writeSortedLog writes n lines one second apart starting at windowBase.
+func writeSortedLog(t *testing.T, n int) string {
+	t.Helper()
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "%s host GET /api/items/%d 200 %d\n",
+			windowBase.Add(time.Duration(i)*time.Second).Format(time.RFC3339), i, i%100)
+	}
+	path := filepath.Join(t.TempDir(), "sorted.log")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseTimeArg(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"":                          {},
+		"2h":                        now.Add(-2 * time.Hour),
+		"2024-03-01T10:00:00Z":      time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		"2024-03-01T10:00:00+02:00": time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC),
+		"2024-03-01 10:00:00":       time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		"2024-03-01":                time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for in, want := range tests {
+		got, err := parseTimeArg("since", in, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseTimeArg(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	var cerr *ConfigError
+	if _, err := parseTimeArg("since", "yesterday", now); !errors.As(err, &cerr) {
+		t.Errorf("bad time err = %v, want ConfigError", err)
+	}
+}
+
+func TestSeekToTime(t *testing.T) {
+	path := writeSortedLog(t, 50000)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, _ := os.ReadFile(path)
+
+	for _, sec := range []int{0, 1, 12345, 49999, 60000} {
+		since := windowBase.Add(time.Duration(sec) * time.Second)
+		offset, err := seekToTime(f, since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target := int64(len(data))
+		if sec < 50000 {
+			target = int64(strings.Index(string(data), since.Format(time.RFC3339)))
+		}
+		if offset > target || target-offset > seekBlockSize+1024 {
+			t.Errorf("since +%ds: offset %d, first wanted line at %d", sec, offset, target)
+		}
+		if offset > 0 && data[offset-1] != '\n' {
+			t.Errorf("since +%ds: offset %d is not a line start", sec, offset)
+		}
+	}
+}
+
+func TestFilterValuesTimeWindow(t *testing.T) {
+	path := writeSortedLog(t, 20000)
+	verbs, _ := parseVerbs("GET")
+	window := TimeWindow{
+		Since: windowBase.Add(15000 * time.Second),
+		Until: windowBase.Add(15100 * time.Second),
+	}
+	for _, sorted := range []bool{false, true} {
+		window.Sorted = sorted
+		c := make(chan LineMatch, ChanSize)
+		go filterValues(path, verbs, window, c)
+		n := 0
+		for range c {
+			n++
+		}
+		if n != 100 {
+			t.Errorf("sorted=%v: matched %d lines, want 100", sorted, n)
+		}
+	}
+}
+
+func TestAssumeYear(t *testing.T) {
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct{ stamp, want string }{
+		{"Jan 2 10:00:00", "2025-01-02T10:00:00Z"},
+		{"Jan 3 08:00:00", "2025-01-03T08:00:00Z"},
+		{"Dec 31 23:59:59", "2024-12-31T23:59:59Z"},
+	}
+	for _, tt := range tests {
+		ts, _ := time.Parse("Jan 2 15:04:05", tt.stamp)
+		if got := assumeYear(ts, now).Format(time.RFC3339); got != tt.want {
+			t.Errorf("assumeYear(%s) = %s, want %s", tt.stamp, got, tt.want)
+		}
+	}
+}
+
+func TestFilterValuesSyslogWindow(t *testing.T) {
+	// syslog stamps have no year and are read as of this year
+	now := time.Now().UTC().Truncate(time.Second)
+	var b strings.Builder
+	for _, ago := range []time.Duration{3 * time.Hour, 2 * time.Hour, 30 * time.Minute, 10 * time.Minute} {
+		fmt.Fprintf(&b, "%s web1 nginx[42]: GET /a 200 %d\n", now.Add(-ago).Format(time.Stamp), ago/time.Minute)
+	}
+	path := filepath.Join(t.TempDir(), "syslog")
+	os.WriteFile(path, []byte(b.String()), 0644)
+	verbs, _ := parseVerbs("GET")
+	for _, window := range []TimeWindow{
+		{Since: now.Add(-time.Hour)},
+		{Since: now.Add(-150 * time.Minute), Until: now.Add(-20 * time.Minute)},
+	} {
+		c := make(chan LineMatch, ChanSize)
+		go filterValues(path, verbs, window, c)
+		n := 0
+		for range c {
+			n++
+		}
+		if n != 2 {
+			t.Errorf("%v to %v: matched %d lines, want 2", window.Since, window.Until, n)
+		}
+	}
+}
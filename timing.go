@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+type Stage int
+
+const (
+	StageRead Stage = iota
+	StageMatch
+	StageParse
+	StageAggregate
+	StagePercentiles
+	numStages
+)
+
+var stageNames = [numStages]string{"read", "match", "parse", "aggregate", "percentiles"}
+
+// StageTimes accumulates wall time per processing stage. Each stage is only
+// ever updated from a single goroutine, so no locking is needed as long as
+// the totals are read after the pipeline has drained.
+type StageTimes struct {
+	Enabled bool
+	Started time.Time
+	Total   [numStages]time.Duration
+}
+
+var stageTimes = StageTimes{Started: time.Now()}
+
+// Now returns the current time, or the zero time when timing is disabled.
+func (t *StageTimes) Now() time.Time {
+	if !t.Enabled {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// Since charges the time elapsed from start to stage and returns the new start.
+func (t *StageTimes) Since(stage Stage, start time.Time) time.Time {
+	if !t.Enabled {
+		return start
+	}
+	now := time.Now()
+	t.Total[stage] += now.Sub(start)
+	return now
+}
+
+func printStageTimes(t *StageTimes) {
+	wall := time.Since(t.Started)
+	summary := fmt.Sprintf("wall: %v,    cpu: %v\n", wall.Round(time.Millisecond), cpuTime().Round(time.Millisecond))
+	for stage, d := range t.Total {
+		summary += fmt.Sprintf("%s: %v (%.1f%%),    ", stageNames[stage],
+			d.Round(time.Millisecond), 100*d.Seconds()/wall.Seconds())
+	}
+	// read+match and parse+aggregate run in separate goroutines, so the
+	// stage totals can add up to more than the wall time.
+	log.Print(summary)
+}
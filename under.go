@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// underThresholds are the -under values, in increasing order.
+var underThresholds []float32
+
+// parseThresholdList parses a comma-separated list of increasing values
+// for option; an empty list is nil.
+func parseThresholdList(option, s string) ([]float32, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var list []float32
+	for _, part := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, &ConfigError{option, fmt.Errorf("bad threshold %q", part)}
+		}
+		if len(list) > 0 && float32(v) <= list[len(list)-1] {
+			return nil, &ConfigError{option, errors.New("thresholds must be increasing")}
+		}
+		list = append(list, float32(v))
+	}
+	return list, nil
+}
+
+// shareUnder returns, for each threshold, the fraction of the sorted
+// values that are at or below it.
+func shareUnder(sorted []float32, thresholds []float32) []float32 {
+	if len(thresholds) == 0 {
+		return nil
+	}
+	shares := make([]float32, len(thresholds))
+	for i, t := range thresholds {
+		n := sort.Search(len(sorted), func(j int) bool { return sorted[j] > t })
+		shares[i] = float32(n) / float32(len(sorted))
+	}
+	return shares
+}
+
+func formatThreshold(t float32) string {
+	return strconv.FormatFloat(float64(t), 'g', -1, 32)
+}
+
+// formatShare prints a fraction as a percentage, or "-" for the -1 of an
+// empty row.
+func formatShare(share float32) string {
+	if share < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", share*100)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShareUnder(t *testing.T) {
+	sorted := []float32{10, 100, 250, 250, 400, 600, 900, 1000}
+	got := shareUnder(sorted, []float32{5, 250, 500, 1000})
+	if want := []float32{0, 0.5, 0.625, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("shareUnder = %v, want %v", got, want)
+	}
+}
+
+func TestParseThresholdList(t *testing.T) {
+	got, err := parseThresholdList("under", "250, 500,1e3")
+	if want := []float32{250, 500, 1000}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("parseThresholdList = %v, %v; want %v", got, err, want)
+	}
+	for _, s := range []string{"500,250", "250,x", "250,250"} {
+		if _, err := parseThresholdList("under", s); err == nil {
+			t.Errorf("parseThresholdList(%q) succeeded", s)
+		}
+	}
+}
+
+func TestUnderColumns(t *testing.T) {
+	underThresholds = []float32{20}
+	defer func() { underThresholds = nil }()
+	values := AggregatedValues{Values: Float32Slice{30, 10, 20, 40}, Accum: 100}
+	summary := computePercentiles(values, []int{50})
+	if want := []float32{0.5}; !reflect.DeepEqual(summary.Under, want) {
+		t.Errorf("Under = %v, want %v", summary.Under, want)
+	}
+	empty := computePercentiles(AggregatedValues{}, []int{50})
+	if want := []float32{-1}; !reflect.DeepEqual(empty.Under, want) {
+		t.Errorf("empty Under = %v, want %v", empty.Under, want)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+)
+
+// inputUnits maps the accepted -input-unit values to the factor that
+// converts them to milliseconds.
+var inputUnits = map[string]float32{"ns": 1e-6, "us": 1e-3, "ms": 1, "s": 1000}
+
+func init() {
+	flagChoices["input-unit"] = []string{"ns", "us", "ms", "s"}
+}
+
+// valueScale multiplies every parsed value; main sets it from -input-unit.
+var valueScale float32 = 1
+
+func parseInputUnit(s string) (float32, error) {
+	if s == "" {
+		return 1, nil
+	}
+	scale, ok := inputUnits[s]
+	if !ok {
+		return 0, &ConfigError{"input-unit", fmt.Errorf("unknown unit %q, want ns, us, ms or s", s)}
+	}
+	return scale, nil
+}
+
+// minGuessValues is the number of values below which guessInputUnit doesn't
+// guess at all.
+const minGuessValues = 10
+
+// guessInputUnit guesses the unit of raw latency values: "s" if nearly all
+// are below 10 and most have a fraction, as in nginx's $request_time, "us"
+// if the median is 100000 or more, and "ms" otherwise.
+func guessInputUnit(values []float32) (string, error) {
+	if len(values) < minGuessValues {
+		return "", errors.New("too few values")
+	}
+	var small, fractional, large int
+	for _, v := range values {
+		if v < 10 {
+			small++
+		}
+		if v != float32(math.Trunc(float64(v))) {
+			fractional++
+		}
+		if v >= 100000 {
+			large++
+		}
+	}
+	switch n := len(values); {
+	case small*10 >= n*9 && fractional*2 >= n:
+		return "s", nil
+	case large*2 >= n:
+		return "us", nil
+	}
+	return "ms", nil
+}
+
+// warnInputUnit logs a warning when values don't look like milliseconds
+// and no -input-unit was given, as reading seconds as milliseconds is off
+// by 1000x.
+func warnInputUnit(values []float32) {
+	unit, err := guessInputUnit(values)
+	if err != nil || unit == "ms" {
+		return
+	}
+	names := map[string]string{"s": "seconds", "us": "microseconds"}
+	log.Printf("values look like %s; pass -input-unit=%s to report milliseconds, or -input-unit=ms to keep them as they are",
+		names[unit], unit)
+}
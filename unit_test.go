@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestGuessInputUnit(t *testing.T) {
+	repeat := func(vals ...float32) []float32 {
+		var out []float32
+		for len(out) < 20 {
+			out = append(out, vals...)
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		values []float32
+		want   string
+	}{
+		{"nginx seconds", repeat(0.003, 0.120, 1.5, 0.045), "s"},
+		{"whole milliseconds", repeat(3, 120, 1500, 45), "ms"},
+		{"fast milliseconds", repeat(1, 2, 3, 4), "ms"},
+		{"fractional milliseconds", repeat(0.5, 1.25, 80.5, 300.75), "ms"},
+		{"microseconds", repeat(120000, 350000, 90000), "us"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := guessInputUnit(tt.values)
+			if err != nil || got != tt.want {
+				t.Errorf("guessInputUnit = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := guessInputUnit([]float32{0.1, 0.2}); err == nil {
+		t.Error("guessed a unit from two values")
+	}
+}
+
+func TestParseInputUnit(t *testing.T) {
+	if scale, err := parseInputUnit("s"); err != nil || scale != 1000 {
+		t.Errorf(`parseInputUnit("s") = %v, %v`, scale, err)
+	}
+	if scale, err := parseInputUnit(""); err != nil || scale != 1 {
+		t.Errorf(`parseInputUnit("") = %v, %v`, scale, err)
+	}
+	if _, err := parseInputUnit("min"); err == nil {
+		t.Error(`parseInputUnit("min") succeeded`)
+	}
+}
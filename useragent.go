@@ -0,0 +1,59 @@
+package main
+
+import "strings"
+
+// userAgentClasses are checked in order against the lowercased user agent;
+// the first class with a matching marker wins, so bots that pretend to be
+// browsers are still classified as bots.
+var userAgentClasses = []struct {
+	Class   string
+	Markers []string
+}{
+	{"bot", []string{"bot", "crawler", "spider", "slurp", "pingdom", "uptime", "monitor", "healthcheck", "headless"}},
+	{"api-client", []string{"curl/", "wget/", "python-requests", "python-urllib", "go-http-client", "okhttp",
+		"axios", "java/", "apache-httpclient", "aws-sdk", "postmanruntime", "node-fetch", "grpc"}},
+	{"mobile", []string{"mobile", "android", "iphone", "ipad", "cfnetwork", "dalvik"}},
+	{"browser", []string{"mozilla/", "opera/"}},
+}
+
+// groupByUserAgent counts each line under the class of its user agent.
+var groupByUserAgent = &Grouping{
+	Label: "user-agent",
+	Keys: func(dst []string, m LineMatch) []string {
+		return append(dst, classifyUserAgent(extractUserAgent(m.Line, m.Object)))
+	},
+}
+
+// extractUserAgent returns the user agent of line: the field of a -format
+// preset that logs one, or else the last double-quoted string in the line,
+// as in the combined log format.
+func extractUserAgent(line string, obj jsonObject) string {
+	if key := formatPresets[*lineFormat].UserAgentKey; key != "" {
+		ua, _ := lineField(line, obj, key)
+		return ua
+	}
+	end := strings.LastIndexByte(line, '"')
+	if end <= 0 {
+		return ""
+	}
+	start := strings.LastIndexByte(line[:end], '"')
+	if start < 0 {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+func classifyUserAgent(ua string) string {
+	if ua == "" || ua == "-" {
+		return "unknown"
+	}
+	ua = strings.ToLower(ua)
+	for _, c := range userAgentClasses {
+		for _, marker := range c.Markers {
+			if strings.Contains(ua, marker) {
+				return c.Class
+			}
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestClassifyUserAgent(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)": "bot",
+		"Mozilla/5.0 (X11; Linux x86_64) HeadlessChrome/120.0 Safari/537.36":       "bot",
+		"curl/8.4.0":         "api-client",
+		"Go-http-client/2.0": "api-client",
+		"okhttp/4.9.3":       "api-client",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile":  "mobile",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile":     "mobile",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/53": "browser",
+		"-":             "unknown",
+		"":              "unknown",
+		"SomethingElse": "unknown",
+	}
+	for ua, want := range tests {
+		if got := classifyUserAgent(ua); got != want {
+			t.Errorf("classifyUserAgent(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
+
+func TestExtractUserAgent(t *testing.T) {
+	line := `1.2.3.4 - - [01/Mar/2024:10:00:00 +0000] "GET /x HTTP/1.1" 200 512 "-" "curl/8.4.0" 0.012`
+	if got := extractUserAgent(line, nil); got != "curl/8.4.0" {
+		t.Errorf("extractUserAgent = %q, want curl/8.4.0", got)
+	}
+	if got := extractUserAgent("GET /x 12", nil); got != "" {
+		t.Errorf("extractUserAgent without quotes = %q, want empty", got)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+//
+// commit and buildDate fall back to the VCS stamp embedded by the go tool.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// features lists the optional inputs and outputs compiled into this binary.
+// Files guarded by build tags append to it from init.
+var features = []string{"input:file"}
+
+func init() {
+	registerCommand(&Command{
+		Name:  "version",
+		Short: "print version, build info and compiled-in features",
+		Run:   runVersion,
+	})
+}
+
+func runVersion(args []string) {
+	if len(args) != 0 {
+		commandUsage(commands["version"])
+	}
+	rev, date := commit, buildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && rev == "":
+				rev = s.Value
+			case s.Key == "vcs.time" && date == "":
+				date = s.Value
+			}
+		}
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+
+	sorted := append([]string(nil), features...)
+	sort.Strings(sorted)
+	fmt.Printf("metrics %s\n", version)
+	fmt.Printf("commit:     %s\n", rev)
+	fmt.Printf("build date: %s\n", date)
+	fmt.Printf("go:         %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("features:   %s\n", strings.Join(sorted, " "))
+}
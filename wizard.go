@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommand(&Command{
+		Name:  "init",
+		Args:  "[-o file] [sample.log]",
+		Short: "interactively build a config file from a sample line",
+		Run:   runInit,
+	})
+}
+
+func runInit(args []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	out := fs.String("o", "metrics.conf", "config file to write")
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		commandUsage(commands["init"])
+	}
+
+	var sample []string
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0)) // not stdin, which answers the questions
+		if err != nil {
+			log.Fatal(err)
+		}
+		r, err := decompress(sniffCompression(f))
+		if err != nil {
+			log.Fatal(err)
+		}
+		sample, err = sampleLines(r, 5)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	in := bufio.NewReader(os.Stdin)
+	config, err := runWizard(in, os.Stdout, sample)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if ask(in, os.Stdout, fmt.Sprintf("Write config to %s?", *out), "y") != "y" {
+		return
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintln(f, "# written by metrics init")
+	writeConfig(f, config)
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("run it with: metrics -config %s <file>\n", *out)
+}
+
+// ask prints question with its default and returns the trimmed answer, or
+// def if the answer is empty.
+func ask(in *bufio.Reader, out io.Writer, question, def string) string {
+	if def != "" {
+		fmt.Fprintf(out, "%s [%s] ", question, def)
+	} else {
+		fmt.Fprintf(out, "%s ", question)
+	}
+	answer, _ := in.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+	return answer
+}
+
+// runWizard asks which tokens of the sample hold the value and the verb,
+// tries the extraction on the sample and returns the resulting config. If
+// sample is empty the user is asked to paste a line.
+func runWizard(in *bufio.Reader, out io.Writer, sample []string) (map[string]string, error) {
+	if len(sample) == 0 {
+		line := ask(in, out, "Paste a sample log line:", "")
+		if line == "" {
+			return nil, errors.New("no sample line given")
+		}
+		sample = []string{line}
+	}
+
+	tokens := strings.Fields(sample[0])
+	fmt.Fprintln(out, "\nTokens of the first sample line:")
+	for i, tok := range tokens {
+		fmt.Fprintf(out, "  %2d  %s\n", i+1, tok)
+	}
+	if format, ok := detectTimestampFormat(sample[0]); ok {
+		fmt.Fprintf(out, "timestamp found in token %d (%s)\n", format.Field+1, format.Layout)
+	}
+	fmt.Fprintln(out)
+
+	valueDefault, verbDefault := 0, 0
+	for i, tok := range tokens {
+		if t := fieldType(tok); t == "float" || t == "int" {
+			valueDefault = i + 1
+		}
+		if verbDefault == 0 && httpMethods[strings.Trim(tok, `"`)] {
+			verbDefault = i + 1
+		}
+	}
+
+	config := make(map[string]string)
+	valueTok, err := pickToken(in, out, "Which token is the value (latency)?", valueDefault, len(tokens))
+	if err != nil {
+		return nil, err
+	}
+	if valueTok != len(tokens) {
+		config["value-field"] = strconv.Itoa(valueTok)
+	}
+	verbTok, err := pickToken(in, out, "Which token is the verb?", verbDefault, len(tokens))
+	if err != nil {
+		return nil, err
+	}
+
+	var seen []string
+	for _, line := range sample {
+		if v, ok := nthField(line, verbTok); ok && !containsString(seen, v) {
+			seen = append(seen, v)
+		}
+	}
+	config["verbs"] = ask(in, out, "Verbs to match, comma separated:", strings.Join(seen, ","))
+
+	fmt.Fprintln(out, "\nTrying it on the sample:")
+	saved := *valueField
+	defer func() { *valueField = saved }()
+	*valueField, _ = strconv.Atoi(config["value-field"])
+	verbs, err := parseVerbs(config["verbs"])
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range sample {
+		verb := "-"
+		for i, v := range verbs.Verbs {
+			if verbs.Matchers[i].Match(line) {
+				verb = v
+				break
+			}
+		}
+		if val, err := parseValue(line, nil); err != nil {
+			fmt.Fprintf(out, "  verb %-8s  %v\n", verb, err)
+		} else {
+			fmt.Fprintf(out, "  verb %-8s  value %g\n", verb, val)
+		}
+	}
+	fmt.Fprintln(out)
+	return config, nil
+}
+
+func pickToken(in *bufio.Reader, out io.Writer, question string, def, max int) (int, error) {
+	defStr := ""
+	if def > 0 {
+		defStr = strconv.Itoa(def)
+	}
+	for tries := 0; tries < 3; tries++ {
+		n, err := strconv.Atoi(ask(in, out, question, defStr))
+		if err == nil && n >= 1 && n <= max {
+			return n, nil
+		}
+		fmt.Fprintf(out, "please enter a token number between 1 and %d\n", max)
+	}
+	return 0, errors.New("no valid token picked")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
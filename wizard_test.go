@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunWizard(t *testing.T) {
+	sample := []string{
+		"2024-03-01T10:00:00Z host1 GET /api/users 12.5 200",
+		"2024-03-01T10:00:01Z host1 POST /api/users 40.25 201",
+	}
+	// accept the suggested value token, pick token 3 as the verb and keep
+	// the verbs seen in the sample
+	in := bufio.NewReader(strings.NewReader("5\n\n\n"))
+	var out strings.Builder
+	config, err := runWizard(in, &out, sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"value-field": "5", "verbs": "GET,POST"}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("config = %v, want %v", config, want)
+	}
+	if !strings.Contains(out.String(), "verb GET       value 12.5") {
+		t.Errorf("live extraction not shown:\n%s", out.String())
+	}
+	if *valueField != 0 {
+		t.Errorf("value-field flag left at %d", *valueField)
+	}
+}
+
+func TestRunWizardPastedLine(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("GET /x 12\n\n\nGET\n"))
+	config, err := runWizard(in, io.Discard, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"verbs": "GET"}; !reflect.DeepEqual(config, want) {
+		t.Errorf("config = %v, want %v", config, want)
+	}
+}
+
+func TestRunWizardGivesUp(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("some words only\n"))
+	if _, err := runWizard(in, io.Discard, nil); err == nil {
+		t.Error("expected an error when no value token can be picked")
+	}
+}